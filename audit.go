@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"tail-burn/burn"
+)
+
+// --- AUDIT LOG ---
+// send, request and daemon take -audit=<file>: a hash-chained JSONL record
+// of the offers they host and every request for them (see burn.AuditLog).
+// tail-burn audit verify checks one.
+
+// openAudit opens the -audit log, or returns nil (no log) for "".
+func openAudit(path string) *burn.AuditLog {
+	if path == "" {
+		return nil
+	}
+	audit, err := burn.OpenAudit(path)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	return audit
+}
+
+func runAudit() int {
+	if len(os.Args) < 4 || os.Args[2] != "verify" {
+		fmt.Println("Usage: tail-burn audit verify <file>")
+		return exitError
+	}
+	return verifyAudit(os.Args[3])
+}
+
+// verifyAudit checks the audit log at path and reports on it.
+func verifyAudit(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return exitError
+	}
+	defer f.Close()
+	res, err := burn.VerifyAudit(f)
+	if err != nil {
+		fmt.Printf("❌ %s: %v (the %d entries before it verify)\n", path, err, res.Entries)
+		return exitError
+	}
+	fmt.Printf("✅ %s: %d entries, chain intact\n", path, res.Entries)
+	if res.Entries > 0 {
+		fmt.Printf("🔗 Last hash: %s\n", res.Last)
+	}
+	return exitOK
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tail-burn/burn"
+)
+
+func TestVerifyAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit := openAudit(path)
+	audit.Record(burn.AuditEntry{Event: "offer_created", Name: "plans.pdf"})
+	audit.Record(burn.AuditEntry{Event: "burned", Reason: burn.ReasonAck})
+	audit.Close()
+	if code := verifyAudit(path); code != exitOK {
+		t.Fatalf("expected an intact log to verify, got exit code %d", code)
+	}
+
+	b, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(b), "plans.pdf", "other.pdf", 1)), 0600)
+	if code := verifyAudit(path); code != exitError {
+		t.Fatalf("expected a tampered log to fail, got exit code %d", code)
+	}
+	if openAudit("") != nil {
+		t.Fatalf("expected no audit log without -audit")
+	}
+}
@@ -0,0 +1,161 @@
+package burn
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// --- ACK AUTHENTICATION ---
+// Every download hands out a per-transfer token in a response header. The
+// kill switch only fires for a POST that carries a token from a transfer that
+// actually delivered the whole payload, from the identity it was served to.
+// If the receiver reports a SHA-256, it must match what that transfer sent.
+
+const (
+	tokenHeader      = "X-Tail-Burn-Token"
+	digestHeader     = "X-Tail-Burn-SHA256"
+	browserAckHeader = "X-Tail-Burn-Browser-Ack" // the landing page's script, which ACKs
+)
+
+var (
+	errAckNoToken       = errors.New("missing transfer token")
+	errAckUnknownToken  = errors.New("unknown transfer token")
+	errAckWrongIdentity = errors.New("token was issued to someone else")
+	errAckIncomplete    = errors.New("transfer did not complete")
+	errAckDigest        = errors.New("digest does not match the offer")
+)
+
+// ackStatus maps an ACK rejection to its HTTP status.
+func ackStatus(err error) int {
+	switch err {
+	case errAckIncomplete, errAckDigest:
+		return http.StatusConflict
+	default:
+		return http.StatusForbidden
+	}
+}
+
+type transferRecord struct {
+	peer   string // peerID of the requester
+	done   bool   // the whole payload went out on (or by the end of) this transfer
+	digest string // SHA-256 of what went out (tar or zip, for archives), if known
+}
+
+// ackLedger remembers the tokens issued for one offer. Each peer holds at
+// most one live token: the one from its latest transfer.
+type ackLedger struct {
+	mu     sync.Mutex
+	issued map[string]*transferRecord
+	latest map[string]string // peerID -> its live token
+}
+
+// issue mints a token for a transfer to peer, revoking the one its previous
+// transfer (an earlier range, resume or retry) was given.
+func (l *ackLedger) issue(peer string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.issued == nil {
+		l.issued = make(map[string]*transferRecord)
+		l.latest = make(map[string]string)
+	}
+	if old, ok := l.latest[peer]; ok {
+		delete(l.issued, old)
+	}
+	l.issued[token] = &transferRecord{peer: peer}
+	l.latest[peer] = token
+	return token, nil
+}
+
+// finish marks token's transfer as having delivered the whole payload,
+// whose SHA-256 is digest ("" if unknown).
+func (l *ackLedger) finish(token, digest string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rec, ok := l.issued[token]; ok {
+		rec.done = true
+		rec.digest = digest
+	}
+}
+
+// redeem validates an ACK from peer, reporting it received got ("" if it
+// doesn't say), and consumes the token, so it can't be replayed.
+func (l *ackLedger) redeem(token, peer, got string) error {
+	if token == "" {
+		return errAckNoToken
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec, ok := l.issued[token]
+	if !ok {
+		return errAckUnknownToken
+	}
+	if !strings.EqualFold(rec.peer, peer) {
+		return errAckWrongIdentity
+	}
+	if !rec.done {
+		return errAckIncomplete
+	}
+	if got != "" && rec.digest != "" && !strings.EqualFold(got, rec.digest) {
+		return errAckDigest
+	}
+	delete(l.issued, token)
+	delete(l.latest, rec.peer)
+	return nil
+}
+
+// ackHandler is the kill switch endpoint shared by every kind of offer.
+// onBurn, if set, runs once the share is burned, before shutdown is signaled.
+func (s *Server) ackHandler(share *recipient, acks *ackLedger, used *atomic.Bool, onBurn func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		peer := peerName(who)
+		if used.Load() {
+			s.logf("⛔️ Rejected ACK from %s: link already burned", peer)
+			http.Error(w, "Gone", http.StatusGone)
+			return
+		}
+		// Optional: the receiver tells us what it got
+		if err := acks.redeem(r.Header.Get(tokenHeader), peerID(who), r.Header.Get(digestHeader)); err != nil {
+			s.logf("⛔️ Rejected ACK from %s: %v", peer, err)
+			http.Error(w, err.Error(), ackStatus(err))
+			return
+		}
+		if !used.CompareAndSwap(false, true) {
+			http.Error(w, "Gone", http.StatusGone)
+			return
+		}
+
+		s.logf("⚡️ ACK received from %s.", peer)
+		s.auditPeer("ack", share.hosted, peerOf(who))
+		if s.OnAck != nil {
+			s.OnAck(share.hosted, peerOf(who))
+		}
+		w.Write([]byte("OK"))
+		if onBurn != nil {
+			onBurn()
+		}
+		select {
+		case share.done <- ReasonAck:
+		default:
+		}
+	}
+}
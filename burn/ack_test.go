@@ -0,0 +1,253 @@
+package burn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helloPayload offers hello.txt, checking ACKs against linkDigest.
+func helloPayload(t *testing.T, linkDigest string) *payload {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return &payload{paths: []string{filePath}, name: "hello.txt", size: 11, linkDigest: linkDigest}
+}
+
+// download fetches the file (or a range of it) and returns the issued token.
+func (f *testShare) download(t *testing.T, rng string) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", f.server.URL+"/secret", nil)
+	req.Header.Set("X-Tail-Burn-Client", "true")
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.Header.Get(tokenHeader)
+}
+
+func (f *testShare) ack(t *testing.T, token, digest string) int {
+	t.Helper()
+	req, _ := http.NewRequest("POST", f.server.URL+"/secret/ack", nil)
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	if digest != "" {
+		req.Header.Set(digestHeader, digest)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("ACK failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (f *testShare) expectAlive(t *testing.T) {
+	t.Helper()
+	select {
+	case reason := <-f.shutdown:
+		t.Fatalf("unexpected burn: %s", reason)
+	default:
+	}
+}
+
+func TestAckRejectsForgedTokens(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	f.download(t, "")
+
+	if got := f.ack(t, "", ""); got != http.StatusForbidden {
+		t.Fatalf("missing token: expected 403, got %d", got)
+	}
+	if got := f.ack(t, strings.Repeat("0", 32), ""); got != http.StatusForbidden {
+		t.Fatalf("forged token: expected 403, got %d", got)
+	}
+	f.expectAlive(t)
+}
+
+func TestAckRejectsOtherIdentity(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	token := f.download(t, "")
+
+	// Someone who saw the URL and sniffed the token still isn't the target.
+	f.client.SetLogin("mallory@example.com")
+	if got := f.ack(t, token, ""); got != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", got)
+	}
+	f.expectAlive(t)
+
+	f.client.SetLogin("target@example.com")
+	if got := f.ack(t, token, ""); got != http.StatusOK {
+		t.Fatalf("expected 200 for the real target, got %d", got)
+	}
+}
+
+func TestAckRequiresCompleteTransfer(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	token := f.download(t, "bytes=0-4")
+
+	if got := f.ack(t, token, ""); got != http.StatusConflict {
+		t.Fatalf("expected 409 for a partial transfer, got %d", got)
+	}
+	f.expectAlive(t)
+
+	token = f.download(t, "bytes=5-")
+	if got := f.ack(t, token, ""); got != http.StatusOK {
+		t.Fatalf("expected 200 once the file is covered, got %d", got)
+	}
+}
+
+func TestAckChecksDigest(t *testing.T) {
+	want := strings.Repeat("ab", 32)
+	f := newTestShare(t, withPayload(helloPayload(t, want)))
+	token := f.download(t, "")
+
+	if got := f.ack(t, token, strings.Repeat("cd", 32)); got != http.StatusConflict {
+		t.Fatalf("expected 409 for a wrong digest, got %d", got)
+	}
+	f.expectAlive(t)
+
+	if got := f.ack(t, token, want); got != http.StatusOK {
+		t.Fatalf("expected 200 for the right digest, got %d", got)
+	}
+}
+
+func TestAckReplayIsRejected(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	token := f.download(t, "")
+
+	if got := f.ack(t, token, ""); got != http.StatusOK {
+		t.Fatalf("expected 200, got %d", got)
+	}
+	<-f.shutdown
+
+	if got := f.ack(t, token, ""); got != http.StatusGone {
+		t.Fatalf("replayed ACK: expected 410, got %d", got)
+	}
+	f.expectAlive(t)
+}
+
+func TestAckReissueRevokesOldToken(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	first := f.download(t, "")
+	second := f.download(t, "")
+	if first == "" || first == second {
+		t.Fatalf("expected a fresh token per transfer, got %q and %q", first, second)
+	}
+
+	if got := f.ack(t, first, ""); got != http.StatusForbidden {
+		t.Fatalf("superseded token: expected 403, got %d", got)
+	}
+	f.expectAlive(t)
+
+	if got := f.ack(t, second, ""); got != http.StatusOK {
+		t.Fatalf("latest token: expected 200, got %d", got)
+	}
+}
+
+func TestAckLedgerKeepsOneTokenPerPeer(t *testing.T) {
+	var l ackLedger
+	for range 100 {
+		if _, err := l.issue("target@example.com"); err != nil {
+			t.Fatalf("issue: %v", err)
+		}
+	}
+	token, _ := l.issue("other@example.com")
+	if n := len(l.issued); n != 2 {
+		t.Fatalf("expected one live token per peer, got %d", n)
+	}
+
+	l.finish(token, "")
+	if err := l.redeem(token, "other@example.com", ""); err != nil {
+		t.Fatalf("redeem: %v", err)
+	}
+	if len(l.issued) != 1 || len(l.latest) != 1 {
+		t.Fatalf("expected the redeemed token to be forgotten, got %d issued, %d latest", len(l.issued), len(l.latest))
+	}
+}
+
+// browserDownload does what the landing page's script does: POST for the
+// payload, announcing that it will ACK. It returns the token and the body.
+func browserDownload(t *testing.T, url string) (string, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", url, nil)
+	req.Header.Set(browserAckHeader, "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	return resp.Header.Get(tokenHeader), body
+}
+
+func TestScriptedBrowserDownloadWaitsForAck(t *testing.T) {
+	old := browserShutdownDelay
+	browserShutdownDelay = time.Millisecond
+	t.Cleanup(func() { browserShutdownDelay = old })
+
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	token, body := browserDownload(t, f.server.URL+"/secret")
+	if string(body) != "hello world" || token == "" {
+		t.Fatalf("unexpected download %q (token %q)", body, token)
+	}
+	// No guessing: the link stays alive until the page confirms the save
+	time.Sleep(20 * time.Millisecond)
+	f.expectAlive(t)
+
+	sum := sha256.Sum256(body)
+	if got := f.ack(t, token, hex.EncodeToString(sum[:])); got != http.StatusOK {
+		t.Fatalf("expected 200 for the page's ACK, got %d", got)
+	}
+	if reason := <-f.shutdown; reason != "Client confirmed receipt" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+}
+
+func TestBrowserAckChecksWhatWasSent(t *testing.T) {
+	src := makeTree(t)
+	tarDigest, _ := archiveDigest([]string{src}, writeTar)
+	zipDigest, _ := archiveDigest([]string{src}, writeZip)
+	f := newTestShare(t, withPayload(&payload{paths: []string{src}, name: "configs", linkDigest: tarDigest, browserDigest: zipDigest}))
+
+	// The browser got the zip, so the tar's digest is not what it received
+	token, _ := browserDownload(t, f.server.URL+"/secret")
+	if got := f.ack(t, token, tarDigest); got != http.StatusConflict {
+		t.Fatalf("expected 409 for the tar digest, got %d", got)
+	}
+	f.expectAlive(t)
+	if got := f.ack(t, token, zipDigest); got != http.StatusOK {
+		t.Fatalf("expected 200 for the zip digest, got %d", got)
+	}
+}
+
+func TestLandingPageConfirmsSave(t *testing.T) {
+	f := newTestShare(t, withPayload(helloPayload(t, "")))
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{`var ackPath = "/secret/ack"`, browserAckHeader, `<form method="POST"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected the landing page to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package burn
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- ARCHIVE PAYLOADS (directories & multiple paths) ---
+// Anything other than a single regular file is streamed as an archive built on
+// the fly: tar for the smart client (keeps modes, trivially streamable) and zip
+// for browsers (opens natively everywhere). Nothing is staged on disk.
+
+// isArchivePayload reports whether paths must be sent as an archive.
+func isArchivePayload(paths []string) bool {
+	if len(paths) != 1 {
+		return true
+	}
+	info, err := os.Stat(paths[0])
+	return err == nil && info.IsDir()
+}
+
+// payloadName is the name shown to the receiver (and the archive base name).
+func payloadName(paths []string) string {
+	if len(paths) == 1 {
+		return filepath.Base(filepath.Clean(paths[0]))
+	}
+	return "tail-burn-bundle"
+}
+
+// checkPayload stats every path and rejects top-level name collisions, which
+// would otherwise clash inside the archive.
+func checkPayload(paths []string) error {
+	seen := make(map[string]string)
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			return err
+		}
+		base := filepath.Base(filepath.Clean(p))
+		if prev, ok := seen[base]; ok {
+			return fmt.Errorf("'%s' and '%s' share the name '%s'", prev, p, base)
+		}
+		seen[base] = p
+	}
+	return nil
+}
+
+// walkPayload calls fn for every directory and regular file under paths.
+// name is the slash-separated archive name, rooted at the path's base name.
+// Symlinks and special files are skipped.
+func walkPayload(paths []string, fn func(name, path string, info fs.FileInfo) error) error {
+	for _, p := range paths {
+		root := filepath.Clean(p)
+		base := filepath.Base(root)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				log.Printf("⚠️  Skipping '%s' (not a regular file)", path)
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := base
+			if rel != "." {
+				name = base + "/" + filepath.ToSlash(rel)
+			}
+			return fn(name, path, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// payloadSize sums the size of every regular file under paths.
+func payloadSize(paths []string) (int64, error) {
+	var total int64
+	err := walkPayload(paths, func(_, _ string, info fs.FileInfo) error {
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+func writeTar(w io.Writer, paths []string) error {
+	tw := tar.NewWriter(w)
+	err := walkPayload(paths, func(name, path string, info fs.FileInfo) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// Reading files bumps atime; keep the stream deterministic so its
+		// digest can be computed up front.
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFileTo(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, paths []string) error {
+	zw := zip.NewWriter(w)
+	err := walkPayload(paths, func(name, path string, info fs.FileInfo) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFileTo(fw, path)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// extractTar unpacks a tail-burn tar stream into dest and returns the
+// top-level names it created. Entries that would escape dest are rejected,
+// and only directories and regular files are written. A top-level entry that
+// already exists in dest is renamed the same way single files are
+// (configs -> configs-1). Skipped entries are noted on status.
+func extractTar(r io.Reader, dest string, status io.Writer) ([]string, error) {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+
+	tr := tar.NewReader(r)
+	roots := make(map[string]string) // archive top-level name -> name on disk
+	var created []string
+
+	type dirMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dirMode
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return created, fmt.Errorf("read archive: %w", err)
+		}
+
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return created, fmt.Errorf("unsafe path in archive: %q", hdr.Name)
+		}
+
+		top, rest, _ := strings.Cut(name, "/")
+		root, ok := roots[top]
+		if !ok {
+			root = filepath.Base(getSafeFilename(filepath.Join(dest, top)))
+			roots[top] = root
+			created = append(created, root)
+		}
+		target := filepath.Join(dest, root, filepath.FromSlash(rest))
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			// Keep the directory writable until everything is extracted.
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return created, err
+			}
+			dirs = append(dirs, dirMode{target, mode})
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return created, err
+			}
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return created, err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return created, fmt.Errorf("download interrupted: %w", err)
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return created, err
+			}
+		default:
+			fmt.Fprintf(status, "⚠️  Skipping unsupported entry '%s'\n", hdr.Name)
+		}
+	}
+
+	// Apply directory modes last, deepest first, so read-only dirs don't
+	// block their own contents.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
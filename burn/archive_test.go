@@ -0,0 +1,193 @@
+package burn
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tail-burn/burn/burntest"
+)
+
+// makeTree builds configs/{app.yml, certs/key.pem (0600), run.sh (0755)}.
+func makeTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "configs")
+	if err := os.MkdirAll(filepath.Join(root, "certs"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := []struct {
+		name string
+		body string
+		mode os.FileMode
+	}{
+		{"app.yml", "port: 80\n", 0644},
+		{"certs/key.pem", "secret", 0600},
+		{"run.sh", "#!/bin/sh\n", 0755},
+	}
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f.name))
+		if err := os.WriteFile(p, []byte(f.body), f.mode); err != nil {
+			t.Fatalf("write %s: %v", f.name, err)
+		}
+		os.Chmod(p, f.mode)
+	}
+	return root
+}
+
+func TestTarRoundTripPreservesPathsAndModes(t *testing.T) {
+	src := makeTree(t)
+
+	var buf bytes.Buffer
+	if err := writeTar(&buf, []string{src}); err != nil {
+		t.Fatalf("writeTar: %v", err)
+	}
+
+	dest := t.TempDir()
+	created, err := extractTar(&buf, dest, io.Discard)
+	if err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	if len(created) != 1 || created[0] != "configs" {
+		t.Fatalf("expected [configs], got %v", created)
+	}
+
+	key := filepath.Join(dest, "configs", "certs", "key.pem")
+	body, err := os.ReadFile(key)
+	if err != nil || string(body) != "secret" {
+		t.Fatalf("expected nested file to round-trip, got %q (%v)", body, err)
+	}
+	for name, want := range map[string]os.FileMode{"certs/key.pem": 0600, "run.sh": 0755} {
+		fi, err := os.Stat(filepath.Join(dest, "configs", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+		if fi.Mode().Perm() != want {
+			t.Errorf("%s: expected mode %v, got %v", name, want, fi.Mode().Perm())
+		}
+	}
+}
+
+func TestExtractTarRenamesExistingRoot(t *testing.T) {
+	src := makeTree(t)
+	var buf bytes.Buffer
+	if err := writeTar(&buf, []string{src}); err != nil {
+		t.Fatalf("writeTar: %v", err)
+	}
+
+	dest := t.TempDir()
+	os.Mkdir(filepath.Join(dest, "configs"), 0755)
+
+	created, err := extractTar(&buf, dest, io.Discard)
+	if err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	if len(created) != 1 || created[0] != "configs-1" {
+		t.Fatalf("expected [configs-1], got %v", created)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "configs-1", "app.yml")); err != nil {
+		t.Fatalf("expected renamed tree: %v", err)
+	}
+}
+
+func TestExtractTarRejectsTraversal(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "/etc/evil", "ok/../../evil.txt"} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+		tw.Write([]byte("evil"))
+		tw.Close()
+
+		parent := t.TempDir()
+		dest := filepath.Join(parent, "dest")
+		if _, err := extractTar(&buf, dest, io.Discard); err == nil {
+			t.Errorf("%q: expected unsafe path error", name)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "evil.txt")); err == nil {
+			t.Errorf("%q: file escaped destination", name)
+		}
+	}
+}
+
+func TestWriteZipMultiplePaths(t *testing.T) {
+	src := makeTree(t)
+	extra := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(extra, []byte("hi"), 0644)
+
+	var buf bytes.Buffer
+	if err := writeZip(&buf, []string{src, extra}); err != nil {
+		t.Fatalf("writeZip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"configs/", "configs/certs/key.pem", "notes.txt"} {
+		if !names[want] {
+			t.Errorf("expected zip entry %q, got %v", want, names)
+		}
+	}
+}
+
+func TestCheckPayloadRejectsDuplicateNames(t *testing.T) {
+	a := filepath.Join(t.TempDir(), "same.txt")
+	b := filepath.Join(t.TempDir(), "same.txt")
+	os.WriteFile(a, nil, 0644)
+	os.WriteFile(b, nil, 0644)
+	if err := checkPayload([]string{a, b}); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+}
+
+func TestReceiveDirectory(t *testing.T) {
+	src := makeTree(t)
+
+	shutdownSignal := make(chan string, 1)
+	secretPath := "/secret"
+
+	mux := http.NewServeMux()
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{src}, name: "configs"},
+		&recipient{target: loginTarget("target@example.com"), secretPath: secretPath, done: shutdownSignal})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Browsers get a zip.
+	resp, err := http.Post(server.URL+secretPath, "application/octet-stream", nil)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), "configs.zip") {
+		t.Fatalf("expected zip for browser, got %q", resp.Header.Get("Content-Disposition"))
+	}
+
+	// Fresh handlers for the smart client (the browser download burned the link).
+	mux = http.NewServeMux()
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{src}, name: "configs"},
+		&recipient{target: loginTarget("target@example.com"), secretPath: secretPath, done: shutdownSignal})
+	server2 := httptest.NewServer(mux)
+	defer server2.Close()
+
+	dest := t.TempDir()
+	if _, err := Receive(context.Background(), server2.URL+secretPath, ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if body, err := os.ReadFile(filepath.Join(dest, "configs", "app.yml")); err != nil || string(body) != "port: 80\n" {
+		t.Fatalf("expected unpacked tree, got %q (%v)", body, err)
+	}
+}
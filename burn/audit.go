@@ -0,0 +1,282 @@
+package burn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- AUDIT LOG ---
+// With Server.Audit set, every offer, request, transfer, ACK and burn is
+// appended to a JSONL file. Each entry carries the hash of the one before
+// it and its own hash over everything else on its line, so editing,
+// reordering or deleting an entry breaks the chain from there on
+// (VerifyAudit). Cutting entries off the end can't be detected from the
+// file alone: keep the last hash somewhere else to anchor it.
+
+// AuditEntry is one line of the audit log. Fields that don't apply are
+// left out.
+type AuditEntry struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Offer     string    `json:"offer,omitempty"` // Hosted.ID
+	Name      string    `json:"name,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Digest    string    `json:"sha256,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitzero"`
+	Expires   time.Time `json:"expires,omitzero"`
+	Identity  string    `json:"identity,omitempty"` // the peer's login (or tagged node)
+	Node      string    `json:"node,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"` // shared routes only; an offer's path is its secret
+	Status    int       `json:"status,omitempty"`
+	Outcome   string    `json:"outcome,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Paths     []string  `json:"paths,omitempty"`
+	Request   int64     `json:"request,omitempty"` // a response's request entry (its Seq)
+	Prev      string    `json:"prev"`              // the previous entry's hash ("" for the first)
+}
+
+// hashField ends every line, with the SHA-256 of the entry's JSON without it.
+const hashField = `,"hash":"`
+
+// AuditLog appends hash-chained entries to a file. A nil AuditLog discards
+// them. Only one process may write a file at a time.
+type AuditLog struct {
+	mu   sync.Mutex
+	f    *os.File
+	seq  int64
+	prev string
+}
+
+// OpenAudit opens (or creates) the audit log at path and continues its
+// chain. It refuses a log that doesn't verify.
+func OpenAudit(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	res, err := VerifyAudit(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	return &AuditLog{f: f, seq: res.Entries, prev: res.Last}, nil
+}
+
+// Record appends e, numbered and chained. It sets e's Time if it is zero.
+func (l *AuditLog) Record(e AuditEntry) error {
+	_, err := l.record(e)
+	return err
+}
+
+// record is Record, returning the Seq e was given.
+func (l *AuditLog) record(e AuditEntry) (int64, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.Seq, e.Prev = l.seq+1, l.prev
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	line := append(body[:len(body)-1], hashField+hash+"\"}\n"...)
+	if _, err := l.f.Write(line); err != nil {
+		return 0, err
+	}
+	if err := l.f.Sync(); err != nil {
+		return 0, err
+	}
+	l.seq, l.prev = e.Seq, hash
+	return e.Seq, nil
+}
+
+// Close closes the file.
+func (l *AuditLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// AuditResult is what VerifyAudit found.
+type AuditResult struct {
+	Entries int64
+	Last    string // the last entry's hash, to anchor the log elsewhere
+}
+
+// VerifyAudit reads an audit log and checks every entry's hash, its link to
+// the entry before and its sequence number. The error names the first entry
+// that fails.
+func VerifyAudit(r io.Reader) (AuditResult, error) {
+	var res AuditResult
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := int64(1); sc.Scan(); line++ {
+		b := sc.Bytes()
+		i := bytes.LastIndex(b, []byte(hashField))
+		if i < 0 || !bytes.HasSuffix(b, []byte(`"}`)) {
+			return res, fmt.Errorf("line %d: no hash", line)
+		}
+		body := append(bytes.Clone(b[:i]), '}')
+		hash := string(b[i+len(hashField) : len(b)-2])
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != hash {
+			return res, fmt.Errorf("line %d: hash mismatch, the entry was altered", line)
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(body, &e); err != nil {
+			return res, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Prev != res.Last {
+			return res, fmt.Errorf("line %d: chain broken, an entry before it was removed, altered or reordered", line)
+		}
+		if e.Seq != line {
+			return res, fmt.Errorf("line %d: sequence number %d, want %d", line, e.Seq, line)
+		}
+		res.Entries, res.Last = line, hash
+	}
+	if err := sc.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// audit records e, logging a failure: a broken audit log doesn't stop an
+// offer. It returns e's Seq (0 if it wasn't written).
+func (s *Server) audit(e AuditEntry) int64 {
+	seq, err := s.Audit.record(e)
+	if err != nil {
+		s.logf("❌ Cannot write audit entry: %v", err)
+	}
+	return seq
+}
+
+// auditPeer records what p did with the offer h.
+func (s *Server) auditPeer(event string, h *Hosted, p Peer) {
+	if s.Audit == nil {
+		return
+	}
+	e := AuditEntry{Event: event, Identity: p.Name, Node: p.Node}
+	if h != nil {
+		e.Offer, e.Name = h.ID(), h.Info().Name
+	}
+	s.audit(e)
+}
+
+// outcome describes an HTTP status for the audit log.
+func outcome(status int) string {
+	switch {
+	case status < 300:
+		return "ok"
+	case status < 400:
+		return "redirect"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "blocked"
+	case status == http.StatusNotFound:
+		return "not found"
+	case status == http.StatusGone:
+		return "gone"
+	case status == http.StatusServiceUnavailable:
+		return "not yet"
+	case status < 500:
+		return "rejected"
+	}
+	return "error"
+}
+
+// auditWriter records the status a handler answered with.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (a *auditWriter) WriteHeader(status int) {
+	if a.status == 0 {
+		a.status = status
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *auditWriter) Write(b []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	return a.ResponseWriter.Write(b)
+}
+
+func (a *auditWriter) Flush() {
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (a *auditWriter) Unwrap() http.ResponseWriter { return a.ResponseWriter }
+
+// auditRequest records r with who sent it, serves it, then records how it
+// ended in a response entry. Whatever the request caused (a transfer, an
+// ACK, a burn) lands between the two, so the chain is in causal order.
+func (s *Server) auditRequest(w http.ResponseWriter, r *http.Request) {
+	e := AuditEntry{Event: "request", Method: r.Method}
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.IP = ip
+	}
+	if rt, ok := s.route(r.URL.Path); ok {
+		e.Offer, e.Name = rt.hosted.ID(), rt.hosted.Info().Name
+	} else if _, pattern := s.mux.Handler(r); pattern != "/" {
+		// Only shared routes: anything else may be a burned offer's secret path
+		e.Path = r.URL.Path
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	if who, err := s.Client.WhoIs(ctx, r.RemoteAddr); err == nil {
+		p := peerOf(who)
+		e.Identity, e.Node = p.Name, p.Node
+	}
+	cancel()
+	seq := s.audit(e)
+
+	aw := &auditWriter{ResponseWriter: w}
+	defer func() {
+		resp := AuditEntry{Event: "response", Request: seq, Offer: e.Offer, Name: e.Name, Path: e.Path, Status: aw.status}
+		// A handler that gives up on a response (a broken stream) panics
+		// with ErrAbortHandler; the response still gets its entry
+		p := recover()
+		switch {
+		case p == http.ErrAbortHandler:
+			resp.Outcome = "aborted"
+		case p != nil:
+			resp.Outcome = "error"
+		default:
+			if resp.Status == 0 {
+				resp.Status = http.StatusOK
+			}
+			resp.Outcome = outcome(resp.Status)
+		}
+		s.audit(resp)
+		if p != nil {
+			panic(p)
+		}
+	}()
+	s.mux.ServeHTTP(aw, r)
+}
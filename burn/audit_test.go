@@ -0,0 +1,212 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+// readAudit returns the audit log's lines.
+func readAudit(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func writeAuditLog(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenAudit(path)
+	if err != nil {
+		t.Fatalf("OpenAudit: %v", err)
+	}
+	for i := range n {
+		if err := l.Record(AuditEntry{Event: "request", Identity: "alice@example.com", Status: 200 + i}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	l.Close()
+	return path
+}
+
+func TestAuditChain(t *testing.T) {
+	path := writeAuditLog(t, 3)
+
+	// Reopening continues the chain
+	l, err := OpenAudit(path)
+	if err != nil {
+		t.Fatalf("OpenAudit: %v", err)
+	}
+	l.Record(AuditEntry{Event: "burned", Reason: ReasonAck})
+	l.Close()
+
+	f, _ := os.Open(path)
+	defer f.Close()
+	res, err := VerifyAudit(f)
+	if err != nil || res.Entries != 4 || len(res.Last) != 64 {
+		t.Fatalf("expected 4 chained entries, got %+v (%v)", res, err)
+	}
+	if !strings.Contains(readAudit(t, path)[3], `"seq":4,`) {
+		t.Fatalf("expected the reopened log to keep counting")
+	}
+}
+
+func TestAuditDetectsTampering(t *testing.T) {
+	path := writeAuditLog(t, 4)
+	lines := readAudit(t, path)
+	for name, tampered := range map[string][]string{
+		"edited":    {lines[0], strings.Replace(lines[1], "alice@", "mallory@", 1), lines[2], lines[3]},
+		"removed":   {lines[0], lines[2], lines[3]},
+		"reordered": {lines[0], lines[2], lines[1], lines[3]},
+		"unhashed":  {lines[0], lines[1][:strings.LastIndex(lines[1], hashField)] + "}", lines[2], lines[3]},
+		"truncated": {lines[0], lines[1][:20]},
+	} {
+		res, err := VerifyAudit(strings.NewReader(strings.Join(tampered, "\n") + "\n"))
+		if err == nil {
+			t.Errorf("%s: expected tampering to be detected", name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "line 2:") || res.Entries != 1 {
+			t.Errorf("%s: expected line 2 to fail after 1 good entry, got %v (%+v)", name, err, res)
+		}
+	}
+
+	// A log that doesn't verify isn't written to
+	os.WriteFile(path, []byte(strings.Join([]string{lines[0], lines[2]}, "\n")+"\n"), 0600)
+	if _, err := OpenAudit(path); err == nil {
+		t.Fatalf("expected a tampered log to be refused")
+	}
+}
+
+func TestServerAudit(t *testing.T) {
+	client := &burntest.Client{}
+	srv := newTestServer(t, client)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAudit(path)
+	if err != nil {
+		t.Fatalf("OpenAudit: %v", err)
+	}
+	defer audit.Close()
+	srv.Audit = audit
+
+	h, err := srv.Add(&Offer{
+		Paths:   []string{writeOfferFile(t, "top secret plans")},
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	link := h.Links()[0].URL
+
+	client.SetLogin("mallory@example.com")
+	Receive(context.Background(), link, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy})
+	client.SetLogin("target@example.com")
+	if _, err := Receive(context.Background(), link, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	<-h.Done()
+
+	var got []AuditEntry
+	b, _ := os.ReadFile(path)
+	if _, err := VerifyAudit(bytes.NewReader(b)); err != nil {
+		t.Fatalf("expected the server's log to verify: %v", err)
+	}
+	for _, line := range readAudit(t, path) {
+		var e AuditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("bad audit line: %v", err)
+		}
+		if e.Offer != "" && e.Offer != h.ID() {
+			t.Fatalf("expected entries for offer %s, got %+v", h.ID(), e)
+		}
+		if e.Event == "request" && strings.Contains(e.Path, strings.TrimPrefix(link, srv.BaseURL)[:8]) {
+			t.Fatalf("the offer's secret path must not be logged: %+v", e)
+		}
+		got = append(got, e)
+	}
+
+	created := got[0]
+	if created.Event != "offer_created" || created.Name != "plans.pdf" || created.Digest != h.digest || created.Target != "target@example.com" || created.Expires.IsZero() {
+		t.Fatalf("unexpected first entry %+v", created)
+	}
+	blocked, refused := got[1], got[2]
+	if blocked.Event != "request" || blocked.Identity != "mallory@example.com" || blocked.IP != "127.0.0.1" || blocked.Method != "GET" || blocked.Offer != h.ID() {
+		t.Fatalf("expected mallory's request, got %+v", blocked)
+	}
+	if refused.Event != "response" || refused.Request != blocked.Seq || refused.Outcome != "blocked" || refused.Status != 403 {
+		t.Fatalf("expected mallory's request to be blocked, got %+v", refused)
+	}
+	var events []string
+	for _, e := range got[3:] {
+		events = append(events, e.Event+":"+e.Outcome+e.Reason)
+	}
+	// Each request is recorded before what it caused; only the burn, which
+	// runs on its own, may come before the ACK's response
+	want := []string{"request:", "transfer:", "response:ok", "request:", "ack:", "burned:" + ReasonAck, "response:ok"}
+	if len(events) == len(want) {
+		slices.Sort(events[5:])
+		slices.Sort(want[5:])
+	}
+	if !slices.Equal(events, want) {
+		t.Fatalf("unexpected entries %v, want %v", events, want)
+	}
+}
+
+func TestAuditAbortedResponse(t *testing.T) {
+	srv := newTestServer(t, burntest.NewClient("target@example.com", ""))
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAudit(path)
+	if err != nil {
+		t.Fatalf("OpenAudit: %v", err)
+	}
+	defer audit.Close()
+	srv.Audit = audit
+
+	h, err := srv.Add(&Offer{
+		Stream:  &failingReader{n: 1 << 20},
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := Receive(context.Background(), h.Links()[0].URL, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err == nil {
+		t.Fatalf("expected the broken stream to fail")
+	}
+	<-h.Done()
+
+	var request, response AuditEntry
+	for _, line := range readAudit(t, path) {
+		var e AuditEntry
+		json.Unmarshal([]byte(line), &e)
+		switch e.Event {
+		case "request":
+			request = e
+		case "response":
+			response = e
+		}
+	}
+	if response.Request != request.Seq || response.Outcome != "aborted" {
+		t.Fatalf("expected an aborted response to request %d, got %+v", request.Seq, response)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	for status, want := range map[int]string{200: "ok", 206: "ok", 308: "redirect", 403: "blocked", 404: "not found", 410: "gone", 503: "not yet", 409: "rejected", 500: "error"} {
+		if got := outcome(status); got != want {
+			t.Errorf("outcome(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package burn hosts and collects one-time drops on a tailnet: files,
+// directories, streams and short secrets that burn once the intended
+// recipient has them.
+//
+// A sender describes a drop as an Offer and hosts it on a Server, either on
+// its own tsnet node (ListenTailnet) or on any net.Listener. Receivers fetch
+// it with Receive, which checks the digest, decrypts and confirms receipt;
+// the confirmation burns the offer. The tail-burn command is a thin CLI on
+// top of this package.
+package burn
+
+import (
+	"errors"
+	"fmt"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+// Why an offer burned, as reported by Hosted.Reason. Callers of Hosted.Burn
+// may add their own.
+const (
+	ReasonAck          = "Client confirmed receipt"
+	ReasonReveal       = "Secret revealed"
+	ReasonBrowser      = "Browser download finished"
+	ReasonUpload       = "Upload received"
+	ReasonCollected    = "All recipients collected"
+	ReasonTimeout      = "Timeout reached"
+	ReasonCodeAttempts = "Too many code attempts"
+	ReasonStream       = "Stream interrupted"
+)
+
+// Delivered reports whether an offer that burned for reason reached its
+// recipients.
+func Delivered(reason string) bool {
+	switch reason {
+	case ReasonAck, ReasonReveal, ReasonBrowser, ReasonUpload, ReasonCollected:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrGone means the offer burned (or expired) before we got it.
+	ErrGone = errors.New("offer burned")
+	// ErrForbidden means we are not allowed to have it: the sender's target
+	// turned us away, the code was wrong, or the server isn't who
+	// ReceiveOptions.From says.
+	ErrForbidden = errors.New("not allowed")
+)
+
+// statusError is an HTTP status the server answered instead of the offer.
+type statusError struct {
+	what   string // "request" or "code"
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server rejected %s: HTTP %d", e.what, e.status)
+}
+
+func (e *statusError) Is(target error) bool {
+	switch e.status {
+	case 401, 403:
+		return target == ErrForbidden
+	case 404, 410:
+		return target == ErrGone
+	}
+	return false
+}
+
+// refusal marks an error that means "not allowed": a wrong code, or a
+// server that isn't who -from says.
+type refusal struct{ error }
+
+func (r refusal) Unwrap() error { return r.error }
+
+func (r refusal) Is(target error) bool { return target == ErrForbidden }
+
+// Peer is who is on the other end of a request.
+type Peer struct {
+	Name   string // login name, or node name for tagged nodes
+	Node   string // the hostname the node registered with
+	NodeID tailcfg.StableNodeID
+}
+
+func peerOf(who *apitype.WhoIsResponse) Peer {
+	p := Peer{Name: peerName(who)}
+	if who.Node != nil {
+		p.Node = nodeHostname(who)
+		p.NodeID = who.Node.StableID
+	}
+	return p
+}
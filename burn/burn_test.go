@@ -0,0 +1,47 @@
+package burn
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+func TestStatusErrorMatches(t *testing.T) {
+	for _, tt := range []struct {
+		status          int
+		forbidden, gone bool
+	}{
+		{401, true, false},
+		{403, true, false},
+		{404, false, true},
+		{410, false, true},
+		{500, false, false},
+	} {
+		err := fmt.Errorf("receive: %w", &statusError{what: "request", status: tt.status})
+		if got := errors.Is(err, ErrForbidden); got != tt.forbidden {
+			t.Errorf("HTTP %d: errors.Is(ErrForbidden) = %v", tt.status, got)
+		}
+		if got := errors.Is(err, ErrGone); got != tt.gone {
+			t.Errorf("HTTP %d: errors.Is(ErrGone) = %v", tt.status, got)
+		}
+	}
+	if err := (refusal{errors.New("wrong code")}); !errors.Is(err, ErrForbidden) || err.Error() != "wrong code" {
+		t.Fatalf("expected a refusal to be forbidden and keep its message, got %v", err)
+	}
+}
+
+func TestPeerOf(t *testing.T) {
+	p := peerOf(&apitype.WhoIsResponse{
+		UserProfile: &tailcfg.UserProfile{LoginName: "alice@example.com"},
+		Node:        &tailcfg.Node{Name: "laptop.tailnet.ts.net.", StableID: "nABC"},
+	})
+	if p != (Peer{Name: "alice@example.com", Node: "laptop", NodeID: "nABC"}) {
+		t.Fatalf("unexpected peer %+v", p)
+	}
+	if p := peerOf(&apitype.WhoIsResponse{UserProfile: &tailcfg.UserProfile{LoginName: "bob@example.com"}}); p != (Peer{Name: "bob@example.com"}) {
+		t.Fatalf("expected a peer without a node, got %+v", p)
+	}
+}
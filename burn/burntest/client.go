@@ -0,0 +1,96 @@
+// Package burntest has fakes for testing code built on the burn package.
+package burntest
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+	"tailscale.com/types/key"
+)
+
+// Client is a fake burn.LocalClient. WhoIs answers with the peer set for the
+// caller's address, else the default peer; Status reports Self and Peers.
+// It is safe to change the peers while requests are being served.
+type Client struct {
+	Self  string                    // this node's login in Status ("" leaves Self out)
+	Peers []*ipnstate.PeerStatus    // the tailnet's other nodes in Status
+	Users map[tailcfg.UserID]string // the logins of the Peers' users
+
+	mu     sync.Mutex
+	who    *apitype.WhoIsResponse
+	byAddr map[netip.Addr]*apitype.WhoIsResponse
+}
+
+// NewClient returns a Client that identifies every caller as login, on a
+// node whose own user is self.
+func NewClient(login, self string) *Client {
+	c := &Client{Self: self}
+	c.SetLogin(login)
+	return c
+}
+
+// NewPeerClient returns a Client that answers every WhoIs with who.
+func NewPeerClient(who *apitype.WhoIsResponse) *Client {
+	c := &Client{}
+	c.SetPeer(who)
+	return c
+}
+
+// SetLogin identifies every caller (without an own SetPeerAt) as login.
+func (c *Client) SetLogin(login string) {
+	c.SetPeer(&apitype.WhoIsResponse{UserProfile: &tailcfg.UserProfile{LoginName: login}})
+}
+
+// SetPeer answers WhoIs for every caller (without an own SetPeerAt) with who.
+func (c *Client) SetPeer(who *apitype.WhoIsResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.who = who
+}
+
+// SetPeerAt answers WhoIs for callers from addr with who.
+func (c *Client) SetPeerAt(addr netip.Addr, who *apitype.WhoIsResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byAddr == nil {
+		c.byAddr = make(map[netip.Addr]*apitype.WhoIsResponse)
+	}
+	c.byAddr[addr] = who
+}
+
+func (c *Client) WhoIs(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ap, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		if who, ok := c.byAddr[ap.Addr()]; ok {
+			return who, nil
+		}
+	}
+	if c.who == nil {
+		return &apitype.WhoIsResponse{}, nil
+	}
+	return c.who, nil
+}
+
+func (c *Client) Status(ctx context.Context) (*ipnstate.Status, error) {
+	st := &ipnstate.Status{
+		Peer: make(map[key.NodePublic]*ipnstate.PeerStatus),
+		User: make(map[tailcfg.UserID]tailcfg.UserProfile),
+	}
+	for id, login := range c.Users {
+		st.User[id] = tailcfg.UserProfile{LoginName: login}
+	}
+	if c.Self != "" {
+		selfID := tailcfg.UserID(1)
+		st.Self = &ipnstate.PeerStatus{UserID: selfID}
+		st.User[selfID] = tailcfg.UserProfile{LoginName: c.Self}
+	}
+	for _, p := range c.Peers {
+		st.Peer[key.NewNode().Public()] = p
+	}
+	return st, nil
+}
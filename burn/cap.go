@@ -0,0 +1,93 @@
+package burn
+
+import (
+	"fmt"
+	"slices"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+// --- ACL CAPABILITY GRANTS ---
+// With -acl, the tailnet policy file decides who may receive drops. WhoIs
+// returns the capabilities the sender's node grants the requester; any grant
+// of Capability whose parameters fit the offer lets the request through:
+//
+//	"grants": [{
+//	  "src": ["group:sre"],
+//	  "dst": ["tag:workstation"],
+//	  "app": {"scopey.dev/cap/tail-burn": [{"maxBytes": 104857600, "classes": ["file"]}]}
+//	}]
+//
+// A grant with no parameters ({}) allows everything.
+
+// Capability is the peer capability Offer.ACL checks for.
+const Capability tailcfg.PeerCapability = "scopey.dev/cap/tail-burn"
+
+// Offer classes a grant can be limited to.
+const (
+	ClassFile    = "file"
+	ClassArchive = "archive"
+	ClassText    = "text" // send -text
+)
+
+// capRule is the JSON parameter object of one tail-burn grant.
+type capRule struct {
+	MaxBytes int64    `json:"maxBytes,omitempty"` // 0 means no limit; streams of unknown size never fit one
+	Classes  []string `json:"classes,omitempty"`  // empty means any class
+}
+
+func (r capRule) permits(size int64, class string) bool {
+	if r.MaxBytes > 0 && (size < 0 || size > r.MaxBytes) {
+		return false
+	}
+	return len(r.Classes) == 0 || slices.Contains(r.Classes, class)
+}
+
+// capTarget authorizes peers that hold a grant fitting this offer.
+type capTarget struct {
+	size  int64 // -1 for a stream
+	class string
+}
+
+func (t capTarget) Allow(who *apitype.WhoIsResponse) bool {
+	rules, err := tailcfg.UnmarshalCapJSON[capRule](who.CapMap, Capability)
+	if err != nil {
+		return false // a malformed grant grants nothing
+	}
+	for _, r := range rules {
+		if r.permits(t.size, t.class) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t capTarget) String() string { return "grant:" + string(Capability) }
+
+// offerClass is the class a grant must allow for this payload.
+func offerClass(paths []string) string {
+	if isArchivePayload(paths) {
+		return ClassArchive
+	}
+	return ClassFile
+}
+
+// withGrant adds the -acl requirement to target (nil: the grant alone
+// decides): with both, a peer needs both.
+func withGrant(target Authorizer, acl bool, size int64, class string) (Authorizer, error) {
+	var terms []Authorizer
+	if target != nil {
+		terms = append(terms, target)
+	}
+	if acl {
+		terms = append(terms, capTarget{size: size, class: class})
+	}
+	switch len(terms) {
+	case 0:
+		return nil, fmt.Errorf("need a target, an ACL grant, or both")
+	case 1:
+		return terms[0], nil
+	}
+	return allOf(terms), nil
+}
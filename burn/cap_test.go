@@ -0,0 +1,105 @@
+package burn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tail-burn/burn/burntest"
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+func grants(rules ...string) tailcfg.PeerCapMap {
+	var raw []tailcfg.RawMessage
+	for _, r := range rules {
+		raw = append(raw, tailcfg.RawMessage(r))
+	}
+	return tailcfg.PeerCapMap{Capability: raw}
+}
+
+func TestCapTargetAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		capMap tailcfg.PeerCapMap
+		size   int64
+		class  string
+		want   bool
+	}{
+		{"no grant", nil, 10, ClassFile, false},
+		{"other capability", tailcfg.PeerCapMap{"example.com/cap/other": {`{}`}}, 10, ClassFile, false},
+		{"bare grant", grants(`{}`), 1 << 40, ClassArchive, true},
+		{"under max size", grants(`{"maxBytes": 100}`), 100, ClassFile, true},
+		{"over max size", grants(`{"maxBytes": 100}`), 101, ClassFile, false},
+		{"stream under bare grant", grants(`{}`), -1, ClassFile, true},
+		{"stream under max size", grants(`{"maxBytes": 100}`), -1, ClassFile, false},
+		{"class allowed", grants(`{"classes": ["file", "archive"]}`), 10, ClassArchive, true},
+		{"class denied", grants(`{"classes": ["file"]}`), 10, ClassArchive, false},
+		{"any grant fits", grants(`{"maxBytes": 5}`, `{"classes": ["file"]}`), 10, ClassFile, true},
+		{"malformed grant", grants(`{"maxBytes": "lots"}`), 10, ClassFile, false},
+	}
+	for _, tt := range tests {
+		who := userPeer("alice@example.com")
+		who.CapMap = tt.capMap
+		if got := (capTarget{size: tt.size, class: tt.class}).Allow(who); got != tt.want {
+			t.Errorf("%s: allow = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithGrant(t *testing.T) {
+	if _, err := withGrant(nil, false, 10, ClassFile); err == nil {
+		t.Fatalf("expected an error with neither -target nor -acl")
+	}
+
+	auth, err := withGrant(loginTarget("alice@example.com"), true, 10, ClassFile)
+	if err != nil {
+		t.Fatalf("withGrant: %v", err)
+	}
+	granted := userPeer("alice@example.com")
+	granted.CapMap = grants(`{}`)
+	if !auth.Allow(granted) {
+		t.Fatalf("expected target with grant to be allowed")
+	}
+	if auth.Allow(userPeer("alice@example.com")) {
+		t.Fatalf("expected -target and -acl to both be required")
+	}
+}
+
+func TestRegisterHandlersCapabilityGrant(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hello.txt")
+	os.WriteFile(filePath, []byte("hello"), 0600)
+	auth, _ := withGrant(nil, true, 5, ClassFile)
+
+	for _, tt := range []struct {
+		name   string
+		capMap tailcfg.PeerCapMap
+		want   int
+	}{
+		{"granted", grants(`{"classes": ["file"]}`), http.StatusOK},
+		{"too big for grant", grants(`{"maxBytes": 4}`), http.StatusForbidden},
+		{"no grant", nil, http.StatusForbidden},
+	} {
+		mux := http.NewServeMux()
+		client := burntest.NewClient("", "sender@example.com")
+		client.SetPeer(&apitype.WhoIsResponse{UserProfile: &tailcfg.UserProfile{LoginName: "anyone@example.com"}, CapMap: tt.capMap})
+		serveShare(mux, client,
+			&payload{paths: []string{filePath}, name: "hello.txt", size: 5},
+			&recipient{target: auth, secretPath: "/secret", done: make(chan string, 1)})
+		server := httptest.NewServer(mux)
+
+		req, _ := http.NewRequest("GET", server.URL+"/secret", nil)
+		req.Header.Set("X-Tail-Burn-Client", "true")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		resp.Body.Close()
+		server.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, resp.StatusCode)
+		}
+	}
+}
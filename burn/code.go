@@ -0,0 +1,290 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// --- SHORT CODES ---
+// Besides the link, send prints a code like 7-crossword-lantern that is easy
+// to read out over a call. The number (the nameplate) is part of the sender's
+// hostname, tail-burn-7-<random>, so receive finds the node among its peers;
+// the words are the password of a PAKE exchange (see pake.go) whose key seals
+// the full link. Every exchange spends one of -code-attempts; the last one
+// is answered (it may have had the right code), and unless the offer is
+// collected within lastCodeGrace it burns.
+
+const (
+	codePath            = "/.tail-burn/code"
+	DefaultCodeAttempts = 3
+	maxNameplate        = 999
+)
+
+// lastCodeGrace is how long the last code exchange's receiver has to collect
+// the offer before it burns.
+var lastCodeGrace = time.Minute
+
+var codePattern = regexp.MustCompile(`^[0-9]+-[a-z]+-[a-z]+$`)
+
+// codeWords are the 256 words a code is made of (8 bits each).
+var codeWords = [256]string{
+	"acorn", "actor", "adobe", "agent", "album", "alpine", "amber", "anchor", "angle",
+	"ankle", "apple", "apron", "arbor", "arcade", "arrow", "artist", "aspen", "atlas",
+	"attic", "autumn", "avenue", "bacon", "badge", "bagel", "bakery", "ballad", "bamboo",
+	"banjo", "banner", "barley", "barrel", "basket", "beacon", "beaver", "bicycle",
+	"biscuit", "blanket", "blossom", "bonfire", "border", "bottle", "bramble", "breeze",
+	"bridge", "brook", "bucket", "buffalo", "bugle", "butter", "button", "cabin", "cactus",
+	"camera", "canal", "candle", "canoe", "canyon", "carbon", "carpet", "castle", "cedar",
+	"cello", "chapel", "cherry", "chimney", "cider", "circus", "citrus", "clover", "cobalt",
+	"comet", "compass", "copper", "coral", "cotton", "cradle", "crater", "crayon",
+	"cricket", "crossword", "crystal", "cupcake", "curtain", "dagger", "daisy", "dancer",
+	"delta", "desert", "diamond", "dolphin", "domino", "dragon", "drum", "eagle", "echo",
+	"eclipse", "elbow", "ember", "engine", "falcon", "feather", "fennel", "ferry", "fiddle",
+	"fig", "flute", "forest", "fossil", "fountain", "galaxy", "garden", "garlic", "geyser",
+	"ginger", "glacier", "goblet", "granite", "grape", "gravel", "guitar", "hammer",
+	"harbor", "harvest", "hazel", "helmet", "hermit", "hickory", "honey", "horizon",
+	"icicle", "igloo", "island", "ivory", "jacket", "jasmine", "jelly", "jigsaw", "jungle",
+	"kayak", "kernel", "kettle", "kitten", "ladder", "lagoon", "lantern", "laurel", "lemon",
+	"lily", "lobster", "locket", "magnet", "mango", "maple", "marble", "meadow", "melon",
+	"meteor", "mirror", "mitten", "mosaic", "muffin", "mustard", "nectar", "needle",
+	"nickel", "noodle", "nutmeg", "oasis", "oatmeal", "ocean", "olive", "onion", "orbit",
+	"orchid", "otter", "oyster", "paddle", "palace", "panda", "parrot", "pebble", "pepper",
+	"piano", "pickle", "pilot", "pine", "planet", "plaza", "pocket", "pony", "poppy",
+	"puzzle", "quartz", "quill", "rabbit", "radar", "raisin", "ranger", "raven", "reef",
+	"ribbon", "river", "rocket", "saddle", "saffron", "salmon", "sandal", "scarf", "shadow",
+	"shovel", "silver", "sketch", "sled", "socket", "spiral", "sponge", "squash", "stamp",
+	"stove", "summit", "sunset", "swan", "tablet", "tango", "teapot", "thimble", "thistle",
+	"thunder", "tiger", "timber", "toast", "tomato", "topaz", "tractor", "trumpet", "tulip",
+	"tunnel", "turnip", "umbrella", "valley", "velvet", "violin", "wagon", "walnut",
+	"walrus", "whistle", "willow", "window", "winter", "wizard", "wombat", "yarn", "yogurt",
+	"zebra", "zephyr", "zigzag",
+}
+
+// IsCode reports whether a receive argument is a short code, not a link.
+func IsCode(s string) bool {
+	return codePattern.MatchString(strings.ToLower(s))
+}
+
+// NewCode picks a random nameplate and two words.
+func NewCode() (nameplate int, code string, err error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(maxNameplate))
+	if err != nil {
+		return 0, "", err
+	}
+	var words [2]byte
+	if _, err := rand.Read(words[:]); err != nil {
+		return 0, "", err
+	}
+	nameplate = int(n.Int64()) + 1
+	return nameplate, fmt.Sprintf("%d-%s-%s", nameplate, codeWords[words[0]], codeWords[words[1]]), nil
+}
+
+// codeHostPrefix is how the nodes serving a nameplate are named.
+func codeHostPrefix(nameplate string) string {
+	return "tail-burn-" + nameplate + "-"
+}
+
+// codeExchange is the body of both directions of a code exchange.
+type codeExchange struct {
+	Msg    []byte `json:"msg"`              // SPAKE2 message
+	Sealed []byte `json:"sealed,omitempty"` // server only: nonce || AES-GCM(link)
+}
+
+// pakeSeal and pakeOpen protect the link with the exchanged key.
+func pakeSeal(key, plaintext []byte) ([]byte, error) {
+	aead, err := pakeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func pakeOpen(key, sealed []byte) ([]byte, error) {
+	aead, err := pakeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errPakeMessage
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+func pakeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// codeHandler answers code exchanges with the link (path and #fragment) of
+// the caller's offer, sealed under the PAKE key. Only peers one of the
+// offers is meant for can spend an attempt; the last one burns h through
+// done after lastCodeGrace, and any after it right away.
+func (s *Server) codeHandler(h *Hosted, offers []recipientOffer, code string, maxAttempts int, done chan string) http.HandlerFunc {
+	var attempts atomic.Int32
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		offer, ok := offerFor(offers, who)
+		if !ok {
+			s.blocked(h, who)
+			http.Error(w, "Forbidden", 403)
+			return
+		}
+
+		// The server can't tell a right code from a wrong one, so every
+		// exchange counts
+		n := int(attempts.Add(1))
+		if n > maxAttempts {
+			s.logf("⛔️ Code attempt from %s over the limit of %d. Burning.", peerName(who), maxAttempts)
+			http.Error(w, "Gone", http.StatusGone)
+			select {
+			case done <- ReasonCodeAttempts:
+			default:
+			}
+			return
+		}
+		s.logf("🪄 Code exchange %d/%d with %s", n, maxAttempts, peerName(who))
+		if n == maxAttempts {
+			// This one may have had the right code, so its receiver gets
+			// a head start; a collected offer has burned before the timer
+			grace := lastCodeGrace
+			defer func() {
+				s.logf("⛔️ Last code attempt used. Burning in %s unless collected.", grace)
+				time.AfterFunc(grace, func() {
+					select {
+					case done <- ReasonCodeAttempts:
+					default:
+					}
+				})
+			}()
+		}
+
+		var req codeExchange
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		state, err := newPake(code, false)
+		if err != nil {
+			http.Error(w, "PAKE Error", http.StatusInternalServerError)
+			return
+		}
+		key, err := state.finish(req.Msg)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		sealed, err := pakeSeal(key, []byte(offer.Link))
+		if err != nil {
+			http.Error(w, "PAKE Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(codeExchange{Msg: state.msg, Sealed: sealed})
+	}
+}
+
+// findCodeNode finds the online tail-burn node serving nameplate.
+func findCodeNode(ctx context.Context, lc LocalClient, nameplate string) (string, error) {
+	st, err := lc.Status(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot list tailnet peers: %w", err)
+	}
+	prefix := codeHostPrefix(nameplate)
+	var hosts []string
+	for _, peer := range st.Peer {
+		if peer.Online && strings.HasPrefix(peer.HostName, prefix) {
+			hosts = append(hosts, strings.TrimSuffix(peer.DNSName, "."))
+		}
+	}
+	switch len(hosts) {
+	case 0:
+		return "", fmt.Errorf("no tail-burn node for code %s is online", nameplate)
+	case 1:
+		return hosts[0], nil
+	}
+	return "", fmt.Errorf("%d offers share code number %s; use the full link", len(hosts), nameplate)
+}
+
+// redeemCode runs the code exchange and returns the full link it unseals.
+func redeemCode(ctx context.Context, code string, opts ReceiveOptions) (string, error) {
+	code = strings.ToLower(code)
+	nameplate, _, _ := strings.Cut(code, "-")
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	host, err := findCodeNode(ctx, opts.localClient(), nameplate)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(opts.status(), "🪄 Found %s for code %s...\n", host, code)
+
+	client, err := transferClient(opts)
+	if err != nil {
+		return "", err
+	}
+	state, err := newPake(code, true)
+	if err != nil {
+		return "", err
+	}
+	body, _ := json.Marshal(codeExchange{Msg: state.msg})
+	// Plain HTTP: a sender with HTTPS redirects, and the redirect keeps the POST
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://"+host+codePath, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("connection failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone:
+		return "", fmt.Errorf("%w: too many code attempts", ErrGone)
+	default:
+		return "", &statusError{what: "code", status: resp.StatusCode}
+	}
+
+	var answer codeExchange
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&answer); err != nil {
+		return "", fmt.Errorf("bad code exchange: %w", err)
+	}
+	key, err := state.finish(answer.Msg)
+	if err != nil {
+		return "", err
+	}
+	link, err := pakeOpen(key, answer.Sealed)
+	if err != nil || !bytes.HasPrefix(link, []byte("/")) {
+		return "", refusal{fmt.Errorf("wrong code (attempts are limited; too many burn the offer)")}
+	}
+	u := resp.Request.URL
+	return u.Scheme + "://" + u.Host + string(link), nil
+}
@@ -0,0 +1,186 @@
+package burn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+	"tailscale.com/ipn/ipnstate"
+)
+
+func TestNewCode(t *testing.T) {
+	seen := map[string]bool{}
+	for range 20 {
+		nameplate, code, err := NewCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nameplate < 1 || nameplate > maxNameplate {
+			t.Fatalf("nameplate %d out of range", nameplate)
+		}
+		if !IsCode(code) || !strings.HasPrefix(code, fmt.Sprintf("%d-", nameplate)) {
+			t.Fatalf("bad code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected random codes")
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	for s, want := range map[string]bool{
+		"7-crossword-lantern":                  true,
+		"123-Apple-Zebra":                      true,
+		"crossword-lantern":                    false,
+		"7-crossword":                          false,
+		"https://tail-burn-ab12.ts.net/a1b2c3": false,
+	} {
+		if got := IsCode(s); got != want {
+			t.Errorf("IsCode(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func codePeers(peers ...*ipnstate.PeerStatus) *burntest.Client {
+	return &burntest.Client{Peers: peers}
+}
+
+func TestFindCodeNode(t *testing.T) {
+	ctx := context.Background()
+	lc := codePeers(
+		&ipnstate.PeerStatus{HostName: "tail-burn-7-ab12", DNSName: "tail-burn-7-ab12.tailnet.ts.net.", Online: true},
+		&ipnstate.PeerStatus{HostName: "tail-burn-77-cd34", DNSName: "tail-burn-77-cd34.tailnet.ts.net.", Online: true},
+		&ipnstate.PeerStatus{HostName: "tail-burn-8-ef56", DNSName: "tail-burn-8-ef56.tailnet.ts.net.", Online: false},
+	)
+	if host, err := findCodeNode(ctx, lc, "7"); err != nil || host != "tail-burn-7-ab12.tailnet.ts.net" {
+		t.Fatalf("findCodeNode(7) = %q, %v", host, err)
+	}
+	if _, err := findCodeNode(ctx, lc, "8"); err == nil {
+		t.Fatalf("expected an offline node to be skipped")
+	}
+
+	dup := codePeers(
+		&ipnstate.PeerStatus{HostName: "tail-burn-7-ab12", Online: true},
+		&ipnstate.PeerStatus{HostName: "tail-burn-7-cd34", Online: true},
+	)
+	if _, err := findCodeNode(ctx, dup, "7"); err == nil || !strings.Contains(err.Error(), "full link") {
+		t.Fatalf("expected ambiguous nameplate error, got %v", err)
+	}
+}
+
+// codeFixture serves a file offer plus its code exchange, and a receive
+// configuration that finds it as tail-burn-7-ab12.
+func codeFixture(t *testing.T, code string, attempts int) (ReceiveOptions, chan string, string) {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	digest, _ := fileDigest(filePath)
+
+	shutdown := make(chan string, 1)
+	mux := http.NewServeMux()
+	client := burntest.NewClient("target@example.com", "sender@example.com")
+	serveShare(mux, client,
+		&payload{paths: []string{filePath}, name: "plans.pdf", size: 16, linkDigest: digest, browserDigest: digest},
+		&recipient{target: loginTarget("target@example.com"), secretPath: "/secret", done: shutdown})
+	mux.HandleFunc(codePath, (&Server{Client: client}).codeHandler(nil, []recipientOffer{{
+		target: loginTarget("target@example.com"),
+		info:   OfferInfo{Link: buildLink("/secret", linkSecrets{digest: digest})},
+	}}, code, attempts, shutdown))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	dest := t.TempDir()
+	return ReceiveOptions{
+		Dir:    dest,
+		Stall:  DefaultStallPolicy,
+		Client: codePeers(&ipnstate.PeerStatus{HostName: "tail-burn-7-ab12", DNSName: "tail-burn-7-ab12.tailnet.ts.net.", Online: true}),
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}, shutdown, dest
+}
+
+func TestReceiveByCode(t *testing.T) {
+	opts, shutdown, dest := codeFixture(t, "7-crossword-lantern", 3)
+	if _, err := Receive(context.Background(), "7-Crossword-Lantern", opts); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "plans.pdf")); string(got) != "top secret plans" {
+		t.Fatalf("expected the file, got %q", got)
+	}
+	if reason := <-shutdown; reason != "Client confirmed receipt" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+}
+
+func TestWrongCodesBurnOffer(t *testing.T) {
+	old := lastCodeGrace
+	lastCodeGrace = 10 * time.Millisecond
+	t.Cleanup(func() { lastCodeGrace = old })
+
+	opts, shutdown, dest := codeFixture(t, "7-crossword-lantern", 2)
+	for range 2 {
+		_, err := Receive(context.Background(), "7-crossword-lanterns", opts)
+		if err == nil || !strings.Contains(err.Error(), "wrong code") {
+			t.Fatalf("expected wrong code, got %v", err)
+		}
+	}
+	// The last attempt burns the offer without anyone trying again
+	select {
+	case reason := <-shutdown:
+		if reason != "Too many code attempts" {
+			t.Fatalf("unexpected shutdown reason %q", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the offer to burn after the last attempt")
+	}
+	_, err := Receive(context.Background(), "7-crossword-lantern", opts)
+	if err == nil || !strings.Contains(err.Error(), "too many code attempts") {
+		t.Fatalf("expected the offer to stay burned, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing saved, got %v", entries)
+	}
+}
+
+func TestRightCodeOnLastAttempt(t *testing.T) {
+	opts, shutdown, dest := codeFixture(t, "7-crossword-lantern", 2)
+	if _, err := Receive(context.Background(), "7-crossword-lanterns", opts); err == nil {
+		t.Fatalf("expected the wrong code to fail")
+	}
+	if _, err := Receive(context.Background(), "7-crossword-lantern", opts); err != nil {
+		t.Fatalf("receive with the last attempt: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "plans.pdf")); string(got) != "top secret plans" {
+		t.Fatalf("expected the file, got %q", got)
+	}
+	if reason := <-shutdown; reason != "Client confirmed receipt" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+}
+
+func TestCodeExchangeRequiresTarget(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(codePath, (&Server{Client: burntest.NewClient("mallory@example.com", "")}).codeHandler(nil,
+		[]recipientOffer{{target: loginTarget("target@example.com"), info: OfferInfo{Link: "/secret"}}},
+		"7-crossword-lantern", 1, make(chan string, 1)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	resp, err := http.Post(server.URL+codePath, "application/json", strings.NewReader(`{"msg":""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package burn
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// privateDir makes sure dir exists, belongs to this user and is closed to
+// everyone else, so nobody else can reach (or swap) a socket inside it.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s belongs to another user", dir)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is open to other users (mode %v); it must be 0700", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// listenUnix listens on path with a umask that keeps the socket private
+// from the moment it exists.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
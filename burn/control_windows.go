@@ -0,0 +1,15 @@
+//go:build windows
+
+package burn
+
+import (
+	"net"
+	"os"
+)
+
+// privateDir makes sure dir exists. Windows has no mode bits to check; the
+// profile directories the socket lives in are private already.
+func privateDir(dir string) error { return os.MkdirAll(dir, 0700) }
+
+// listenUnix listens on path.
+func listenUnix(path string) (net.Listener, error) { return net.Listen("unix", path) }
@@ -0,0 +1,216 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testAEADKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := newOfferKey()
+	if err != nil {
+		t.Fatalf("newOfferKey: %v", err)
+	}
+	return key
+}
+
+func TestEncryptStreamRoundTrip(t *testing.T) {
+	key := testAEADKey(t)
+	salt := fileSalt([]byte("offer"), `"etag"`)
+	aead, err := streamAEAD(key, salt)
+	if err != nil {
+		t.Fatalf("streamAEAD: %v", err)
+	}
+
+	for _, size := range []int{0, 100, encChunkSize, 2*encChunkSize + 5} {
+		plain := bytes.Repeat([]byte{'p'}, size)
+
+		var sealed bytes.Buffer
+		enc := newEncryptWriter(&sealed, aead)
+		enc.Write(plain)
+		if err := enc.Close(); err != nil {
+			t.Fatalf("size %d: close: %v", size, err)
+		}
+		if int64(sealed.Len()) != ciphertextSize(int64(size)) {
+			t.Fatalf("size %d: ciphertext is %d bytes, expected %d", size, sealed.Len(), ciphertextSize(int64(size)))
+		}
+
+		// The seekable file view must produce the exact same bytes.
+		virtual, err := io.ReadAll(newEncryptedFile(bytes.NewReader(plain), int64(size), aead))
+		if err != nil || !bytes.Equal(virtual, sealed.Bytes()) {
+			t.Fatalf("size %d: encryptedFile differs from encryptWriter (%v)", size, err)
+		}
+
+		got, err := io.ReadAll(newDecryptReader(&sealed, aead, 0))
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip failed: %v", size, err)
+		}
+	}
+}
+
+func TestDecryptDetectsTruncationAndTampering(t *testing.T) {
+	aead, _ := streamAEAD(testAEADKey(t), fileSalt([]byte("offer"), `"etag"`))
+	plain := bytes.Repeat([]byte{'x'}, 2*encChunkSize+10)
+	var sealed bytes.Buffer
+	enc := newEncryptWriter(&sealed, aead)
+	enc.Write(plain)
+	enc.Close()
+	ct := sealed.Bytes()
+
+	// Dropping the final chunk leaves a stream that ends on a full chunk.
+	_, err := io.ReadAll(newDecryptReader(bytes.NewReader(ct[:2*(encChunkSize+encTagSize)]), aead, 0))
+	if !errors.Is(err, errStreamTruncated) {
+		t.Fatalf("expected truncation error, got %v", err)
+	}
+
+	// Cutting mid-chunk makes that chunk look final, which fails to open.
+	if _, err := io.ReadAll(newDecryptReader(bytes.NewReader(ct[:encChunkSize]), aead, 0)); err == nil {
+		t.Fatalf("expected mid-chunk truncation to fail")
+	}
+
+	tampered := bytes.Clone(ct)
+	tampered[10] ^= 1
+	if _, err := io.ReadAll(newDecryptReader(bytes.NewReader(tampered), aead, 0)); err == nil {
+		t.Fatalf("expected tampered chunk to fail")
+	}
+}
+
+func TestEncryptedFileSeek(t *testing.T) {
+	aead, _ := streamAEAD(testAEADKey(t), fileSalt([]byte("offer"), `"etag"`))
+	plain := bytes.Repeat([]byte("0123456789"), encChunkSize/5)
+	full, _ := io.ReadAll(newEncryptedFile(bytes.NewReader(plain), int64(len(plain)), aead))
+
+	ef := newEncryptedFile(bytes.NewReader(plain), int64(len(plain)), aead)
+	off := int64(encChunkSize + encTagSize + 7)
+	if _, err := ef.Seek(off, io.SeekStart); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	tail, _ := io.ReadAll(ef)
+	if !bytes.Equal(tail, full[off:]) {
+		t.Fatalf("seeked read does not match the full stream")
+	}
+}
+
+func TestFileSaltDependsOnOffer(t *testing.T) {
+	a, b := []byte("offer-a"), []byte("offer-b")
+	if bytes.Equal(fileSalt(a, `"etag"`), fileSalt(b, `"etag"`)) {
+		t.Fatalf("two offers of the same file version share a salt")
+	}
+	if bytes.Equal(fileSalt(a, `"v1"`), fileSalt(a, `"v2"`)) {
+		t.Fatalf("two versions of a file in one offer share a salt")
+	}
+	if !bytes.Equal(fileSalt(a, `"etag"`), fileSalt(a, `"etag"`)) {
+		t.Fatalf("salt must be deterministic so ranges line up")
+	}
+}
+
+func TestReceiveEncryptedFile(t *testing.T) {
+	plain := bytes.Repeat([]byte("secret!"), 30000)
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, plain, 0600)
+	digest, _ := fileDigest(filePath)
+	key := testAEADKey(t)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf", key: key, salt: []byte("offer-salt")}))
+	dest := t.TempDir()
+	link := buildLink(f.server.URL+"/secret", linkSecrets{digest: digest, key: key})
+	if _, err := Receive(context.Background(), link, ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "plans.pdf"))
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("decrypted file mismatch (%v)", err)
+	}
+}
+
+func TestReceiveEncryptedArchive(t *testing.T) {
+	src := makeTree(t)
+	digest, _ := archiveDigest([]string{src}, writeTar)
+	key := testAEADKey(t)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{src}, name: "configs", key: key, salt: []byte("offer-salt")}))
+	dest := t.TempDir()
+	link := buildLink(f.server.URL+"/secret", linkSecrets{digest: digest, key: key})
+	if _, err := Receive(context.Background(), link, ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "configs")); err != nil {
+		t.Fatalf("expected extracted directory: %v", err)
+	}
+}
+
+func TestReceiveEncryptedResumesOnChunkBoundary(t *testing.T) {
+	plain := bytes.Repeat([]byte("abcdefgh"), 20000) // 160000 bytes, three chunks
+	filePath := filepath.Join(t.TempDir(), "data.bin")
+	os.WriteFile(filePath, plain, 0600)
+	fi, _ := os.Stat(filePath)
+	key := testAEADKey(t)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "data.bin", key: key, salt: []byte("offer-salt")}))
+
+	// An earlier attempt got a little past the first chunk.
+	dest := t.TempDir()
+	partPath := filepath.Join(dest, "data.bin.part")
+	os.WriteFile(partPath, plain[:encChunkSize+100], 0644)
+	os.WriteFile(partETagPath(partPath), []byte(fileETag(fi)), 0644)
+
+	link := buildLink(f.server.URL+"/secret", linkSecrets{key: key})
+	if _, err := Receive(context.Background(), link, ReceiveOptions{Dir: dest, Resume: true, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	want := "bytes=65552-" // one full ciphertext chunk
+	if ranges := f.downloads(); len(ranges) != 1 || ranges[0] != want {
+		t.Fatalf("expected range %q, got %v", want, ranges)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "data.bin"))
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("resumed file mismatch (%v)", err)
+	}
+}
+
+func TestReceiveEncryptedRequiresKey(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret"), 0600)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf", key: testAEADKey(t), salt: []byte("offer-salt")}))
+	dest := t.TempDir()
+	_, err := Receive(context.Background(), f.server.URL+"/secret", ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing written, got %v", entries)
+	}
+}
+
+func TestReceiveRefusesPlaintextDowngrade(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret"), 0600)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf", salt: []byte("offer-salt")}))
+	link := buildLink(f.server.URL+"/secret", linkSecrets{key: testAEADKey(t)})
+	_, err := Receive(context.Background(), link, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "plaintext") {
+		t.Fatalf("expected downgrade to be refused, got %v", err)
+	}
+}
+
+func TestLandingPageMarksEncryptedOffer(t *testing.T) {
+	f := newTestShare(t, withPayload(&payload{paths: []string{"unused"}, name: "plans.pdf", key: testAEADKey(t), salt: []byte("offer-salt")}))
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(strings.Join(strings.Fields(string(body)), " "), "var encrypted = true") {
+		t.Fatalf("expected landing page to enable in-browser decryption")
+	}
+}
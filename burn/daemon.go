@@ -0,0 +1,475 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- DAEMON ---
+// tail-burn daemon keeps one node up and hosts any number of offers on it,
+// so a send doesn't have to join the tailnet with a fresh ephemeral node
+// every time. Offers are added, listed and revoked over a Unix-domain
+// control socket that only its owner can open; each keeps its own secret
+// path, target and burn state. Offers added through the daemon have no
+// short code: the code's nameplate would have to be in the node's name.
+
+// ReasonRevoked is why an offer revoked through the control socket burned.
+const ReasonRevoked = "Revoked"
+
+// OfferSpec is an offer as sent over the control socket. Paths must be
+// absolute: the daemon resolves them, not the sender.
+type OfferSpec struct {
+	Paths        []string  `json:"paths,omitempty"`
+	Text         []byte    `json:"text,omitempty"` // a one-time secret
+	Target       string    `json:"target,omitempty"`
+	PerRecipient bool      `json:"perRecipient,omitempty"` // a link per Target term
+	ACL          bool      `json:"acl,omitempty"`
+	Encrypt      bool      `json:"encrypt,omitempty"`
+	Wipe         bool      `json:"wipe,omitempty"` // delete Paths once delivered (see Delivered)
+	NotBefore    time.Time `json:"notBefore,omitzero"`
+	Expires      time.Time `json:"expires"`
+}
+
+// offer turns the spec into an Offer.
+func (spec OfferSpec) offer() (*Offer, error) {
+	for _, p := range spec.Paths {
+		if !filepath.IsAbs(p) {
+			return nil, fmt.Errorf("path %q is not absolute", p)
+		}
+	}
+	if spec.Wipe && len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("wipe needs paths")
+	}
+	// The Offer zeroes its secret once burned; the caller's copy is its own
+	o := &Offer{Paths: spec.Paths, Text: bytes.Clone(spec.Text), ACL: spec.ACL, Encrypt: spec.Encrypt, NotBefore: spec.NotBefore, Expires: spec.Expires}
+	if spec.Target == "" && !spec.ACL {
+		return nil, fmt.Errorf("an offer needs a target or acl")
+	}
+	var err error
+	if spec.PerRecipient {
+		o.Recipients, err = ParseTargets(spec.Target)
+	} else if spec.Target != "" {
+		o.Target, err = ParseTarget(spec.Target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+	return o, nil
+}
+
+// OfferStatus describes an offer the daemon hosts.
+type OfferStatus struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Class     string    `json:"class"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	Digest    string    `json:"sha256"`
+	Links     []Link    `json:"links"`
+	NotBefore time.Time `json:"notBefore,omitzero"`
+	Expires   time.Time `json:"expires"`
+	Collected []string  `json:"collected,omitempty"` // recipients that have, for a per-recipient offer
+	Pending   []string  `json:"pending,omitempty"`
+	Added     time.Time `json:"added"`
+}
+
+// DaemonStatus describes a running daemon.
+type DaemonStatus struct {
+	Node        string        `json:"node"`
+	URL         string        `json:"url"`
+	PID         int           `json:"pid"`
+	Started     time.Time     `json:"started"`
+	Offers      int           `json:"offers"`
+	IdleTimeout time.Duration `json:"idleTimeout"` // 0: never
+	IdleSince   time.Time     `json:"idleSince,omitzero"`
+}
+
+// Daemon hosts offers on Server for clients of its control socket. Set
+// Server (listening already) before serving the socket.
+type Daemon struct {
+	Server      *Server
+	Node        string        // the node's name, for Status
+	IdleTimeout time.Duration // Idle returns after this long without offers (0: never)
+
+	setup     sync.Once
+	mu        sync.Mutex
+	offers    map[string]*daemonOffer
+	started   time.Time
+	idleSince time.Time
+	wake      chan struct{}
+}
+
+// daemonOffer is an offer the daemon hosts.
+type daemonOffer struct {
+	id     string
+	spec   OfferSpec // without its Text
+	h      *Hosted
+	digest string
+	added  time.Time
+}
+
+func (d *Daemon) init() {
+	d.setup.Do(func() {
+		d.offers = make(map[string]*daemonOffer)
+		d.started = time.Now()
+		d.idleSince = d.started
+		d.wake = make(chan struct{}, 1)
+	})
+}
+
+// poke restarts the idle countdown.
+func (d *Daemon) poke() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Add hosts spec until it burns.
+func (d *Daemon) Add(spec OfferSpec) (OfferStatus, error) {
+	d.init()
+	o, err := spec.offer()
+	if err != nil {
+		return OfferStatus{}, err
+	}
+	h, err := d.Server.Add(o)
+	if err != nil {
+		return OfferStatus{}, err
+	}
+	spec.Text = nil // the Offer holds its own copy
+	do := &daemonOffer{id: h.ID(), spec: spec, h: h, digest: o.Digest(), added: time.Now()}
+
+	d.mu.Lock()
+	d.offers[do.id] = do
+	d.idleSince = time.Time{}
+	d.mu.Unlock()
+	d.poke()
+	var recipients []string
+	for _, l := range h.Links() {
+		recipients = append(recipients, l.Recipient)
+	}
+	d.Server.logf("📥 Offer %s: %s for %s", do.id, h.Info().Name, strings.Join(recipients, ", "))
+
+	go d.finish(do)
+	return do.status(), nil
+}
+
+// finish forgets an offer once it burned and wipes its paths if asked to.
+func (d *Daemon) finish(do *daemonOffer) {
+	<-do.h.Done()
+	reason := do.h.Reason()
+	d.Server.logf("🔥 Offer %s burned: %s", do.id, reason)
+	if do.spec.Wipe && Delivered(reason) {
+		var wiped []string
+		for _, p := range do.spec.Paths {
+			if err := os.RemoveAll(p); err != nil {
+				d.Server.logf("❌ Failed to wipe %s: %v", p, err)
+				continue
+			}
+			wiped = append(wiped, p)
+		}
+		d.Server.audit(AuditEntry{Event: "wiped", Offer: do.id, Paths: wiped})
+	}
+	d.mu.Lock()
+	delete(d.offers, do.id)
+	if len(d.offers) == 0 {
+		d.idleSince = time.Now()
+	}
+	d.mu.Unlock()
+	d.poke()
+}
+
+func (do *daemonOffer) status() OfferStatus {
+	info := do.h.Info()
+	got, pending := do.h.Collected()
+	return OfferStatus{
+		ID:        do.id,
+		Name:      info.Name,
+		Size:      info.Size,
+		Class:     info.Class,
+		Encrypted: info.Encrypted,
+		Digest:    do.digest,
+		Links:     do.h.Links(),
+		NotBefore: info.NotBefore,
+		Expires:   info.Expires,
+		Collected: got,
+		Pending:   pending,
+		Added:     do.added,
+	}
+}
+
+// List describes the offers that haven't burned, oldest first.
+func (d *Daemon) List() []OfferStatus {
+	d.init()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := make([]OfferStatus, 0, len(d.offers))
+	for _, do := range d.offers {
+		list = append(list, do.status())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Added.Before(list[j].Added) })
+	return list
+}
+
+// Revoke burns the offer with id. It fails with ErrGone if there is none
+// (any more).
+func (d *Daemon) Revoke(id string) error {
+	d.init()
+	d.mu.Lock()
+	do := d.offers[id]
+	d.mu.Unlock()
+	if do == nil {
+		return fmt.Errorf("%w: no offer %s", ErrGone, id)
+	}
+	do.h.Burn(ReasonRevoked)
+	return nil
+}
+
+// BurnAll burns every offer, for a daemon shutting down.
+func (d *Daemon) BurnAll(reason string) {
+	for _, h := range d.Server.Hosted() {
+		h.Burn(reason)
+	}
+}
+
+// Status describes the daemon.
+func (d *Daemon) Status() DaemonStatus {
+	d.init()
+	d.Server.mu.Lock()
+	base := d.Server.BaseURL
+	d.Server.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return DaemonStatus{
+		Node:        d.Node,
+		URL:         base,
+		PID:         os.Getpid(),
+		Started:     d.started,
+		Offers:      len(d.offers),
+		IdleTimeout: d.IdleTimeout,
+		IdleSince:   d.idleSince,
+	}
+}
+
+// Idle returns once the daemon has hosted no offers for IdleTimeout (never,
+// if it is 0), or when ctx ends.
+func (d *Daemon) Idle(ctx context.Context) {
+	d.init()
+	if d.IdleTimeout <= 0 {
+		<-ctx.Done()
+		return
+	}
+	timer := time.NewTimer(d.IdleTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-timer.C:
+			d.mu.Lock()
+			idle := len(d.offers) == 0 && time.Since(d.idleSince) >= d.IdleTimeout
+			d.mu.Unlock()
+			if idle {
+				return
+			}
+		}
+		timer.Reset(d.IdleTimeout)
+	}
+}
+
+// --- CONTROL SOCKET ---
+
+// controlHost is the placeholder host of control requests; the socket is
+// all that matters.
+const controlHost = "http://tail-burn"
+
+// DefaultControlSocket is where the daemon listens unless told otherwise:
+// in $XDG_RUNTIME_DIR, or a private directory in the user config directory.
+func DefaultControlSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "tail-burn.sock")
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+	return filepath.Join(configDir, "tail-burn", "daemon.sock")
+}
+
+// ListenControl listens on the control socket at path, readable only by
+// this user. Its directory must be private to this user too. A socket left
+// behind by a daemon that is gone is replaced; a running daemon's is not.
+func ListenControl(path string) (net.Listener, error) {
+	if err := privateDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("control socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if _, err := NewControlClient(path).Status(context.Background()); err == nil {
+			return nil, fmt.Errorf("a daemon is already running on %s", path)
+		}
+		os.Remove(path)
+	}
+	ln, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// ServeControl answers control requests on ln until it is closed.
+// Connections from other users' processes are dropped.
+func (d *Daemon) ServeControl(ln net.Listener) error {
+	d.init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Status())
+	})
+	mux.HandleFunc("GET /offers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.List())
+	})
+	mux.HandleFunc("POST /offers", func(w http.ResponseWriter, r *http.Request) {
+		var spec OfferSpec
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&spec); err != nil {
+			http.Error(w, "bad offer: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		st, err := d.Add(spec)
+		clear(spec.Text)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, st)
+	})
+	mux.HandleFunc("DELETE /offers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := d.Revoke(r.PathValue("id")); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	if err := srv.Serve(ownerListener{ln, d.Server.logf}); !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// ownerListener drops connections that fail checkPeer.
+type ownerListener struct {
+	net.Listener
+	logf func(string, ...any)
+}
+
+func (l ownerListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPeer(c); err != nil {
+			l.logf("⛔️ Control connection refused: %v", err)
+			c.Close()
+			continue
+		}
+		return c, nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ControlClient talks to a daemon over its control socket.
+type ControlClient struct {
+	http *http.Client
+}
+
+// NewControlClient returns a client for the daemon listening on path.
+func NewControlClient(path string) *ControlClient {
+	return &ControlClient{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// do sends a control request and decodes the answer into out (if not nil).
+// A 404 matches ErrGone.
+func (c *ControlClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		defer clear(b)
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, controlHost+path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("no daemon running (start one with tail-burn daemon): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		text := strings.TrimSpace(string(msg))
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrGone, strings.TrimPrefix(text, ErrGone.Error()+": "))
+		}
+		return fmt.Errorf("daemon: %s", text)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Add hosts spec on the daemon.
+func (c *ControlClient) Add(ctx context.Context, spec OfferSpec) (OfferStatus, error) {
+	var st OfferStatus
+	err := c.do(ctx, "POST", "/offers", spec, &st)
+	return st, err
+}
+
+// List describes the daemon's offers.
+func (c *ControlClient) List(ctx context.Context) ([]OfferStatus, error) {
+	var list []OfferStatus
+	err := c.do(ctx, "GET", "/offers", nil, &list)
+	return list, err
+}
+
+// Revoke burns the offer with id.
+func (c *ControlClient) Revoke(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/offers/"+id, nil, nil)
+}
+
+// Status describes the daemon.
+func (c *ControlClient) Status(ctx context.Context) (DaemonStatus, error) {
+	var st DaemonStatus
+	err := c.do(ctx, "GET", "/status", nil, &st)
+	return st, err
+}
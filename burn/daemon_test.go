@@ -0,0 +1,244 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+// controlSocket is a socket path in a directory only this user can enter.
+func controlSocket(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// startDaemon runs a daemon whose offers are served over plain HTTP, with
+// its control socket in a temp dir.
+func startDaemon(t *testing.T, client LocalClient, idle time.Duration) (*Daemon, *ControlClient) {
+	t.Helper()
+	d := &Daemon{Server: newTestServer(t, client), Node: "tail-burn-daemon-test", IdleTimeout: idle}
+	socket := controlSocket(t, "ctl.sock")
+	ln, err := ListenControl(socket)
+	if err != nil {
+		t.Fatalf("ListenControl: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go d.ServeControl(ln)
+	return d, NewControlClient(socket)
+}
+
+func TestDaemonOffers(t *testing.T) {
+	client := &burntest.Client{}
+	client.SetLogin("alice@example.com")
+	d, ctl := startDaemon(t, client, 0)
+	ctx := context.Background()
+
+	first, err := ctl.Add(ctx, OfferSpec{
+		Paths:   []string{writeOfferFile(t, "top secret plans")},
+		Target:  "alice@example.com",
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	second, err := ctl.Add(ctx, OfferSpec{
+		Text:    []byte("hunter2"),
+		Target:  "bob@example.com",
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if first.ID == second.ID || first.Links[0].URL == second.Links[0].URL {
+		t.Fatalf("expected offers with their own id and path, got %+v and %+v", first, second)
+	}
+	if first.Name != "plans.pdf" || first.Size != 16 || first.Digest == "" || first.Links[0].Recipient != "alice@example.com" {
+		t.Fatalf("unexpected offer %+v", first)
+	}
+
+	list, err := ctl.List(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Fatalf("expected both offers, oldest first, got %+v (%v)", list, err)
+	}
+	st, err := ctl.Status(ctx)
+	if err != nil || st.Offers != 2 || st.Node != "tail-burn-daemon-test" || st.URL != d.Server.BaseURL || st.PID != os.Getpid() {
+		t.Fatalf("unexpected status %+v (%v)", st, err)
+	}
+
+	// Alice collects hers; Bob's stays up
+	if _, err := Receive(ctx, first.Links[0].URL, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	waitFor(t, func() bool { list, _ := ctl.List(ctx); return len(list) == 1 })
+
+	if err := ctl.Revoke(ctx, second.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	client.SetLogin("bob@example.com")
+	if _, err := Receive(ctx, second.Links[0].URL, ReceiveOptions{Stall: DefaultStallPolicy}); !errors.Is(err, ErrGone) {
+		t.Fatalf("expected a revoked offer to be gone, got %v", err)
+	}
+	if err := ctl.Revoke(ctx, second.ID); !errors.Is(err, ErrGone) {
+		t.Fatalf("expected revoking twice to be gone, got %v", err)
+	}
+	waitFor(t, func() bool { list, _ := ctl.List(ctx); return len(list) == 0 })
+}
+
+func TestDaemonDeliversSecret(t *testing.T) {
+	_, ctl := startDaemon(t, burntest.NewClient("alice@example.com", ""), 0)
+	ctx := context.Background()
+
+	st, err := ctl.Add(ctx, OfferSpec{
+		Text:    []byte("hunter2"),
+		Target:  "alice@example.com",
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	res, err := Receive(ctx, st.Links[0].URL, ReceiveOptions{Stall: DefaultStallPolicy})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if string(res.Secret) != "hunter2" {
+		t.Fatalf("expected the secret, got %q", res.Secret)
+	}
+}
+
+func TestDaemonRejectsBadOffers(t *testing.T) {
+	_, ctl := startDaemon(t, burntest.NewClient("alice@example.com", ""), 0)
+	path := writeOfferFile(t, "top secret plans")
+	for name, spec := range map[string]OfferSpec{
+		"relative path": {Paths: []string{"plans.pdf"}, Target: "alice@example.com", Expires: time.Now().Add(time.Minute)},
+		"no target":     {Paths: []string{path}, Expires: time.Now().Add(time.Minute)},
+		"bad target":    {Paths: []string{path}, Target: "any-of(", Expires: time.Now().Add(time.Minute)},
+		"expired":       {Paths: []string{path}, Target: "alice@example.com", Expires: time.Now().Add(-time.Minute)},
+		"wiped secret":  {Text: []byte("hunter2"), Wipe: true, Target: "alice@example.com", Expires: time.Now().Add(time.Minute)},
+	} {
+		if _, err := ctl.Add(context.Background(), spec); err == nil {
+			t.Errorf("%s: expected the daemon to refuse the offer", name)
+		}
+	}
+}
+
+func TestDaemonWipesDeliveredOffers(t *testing.T) {
+	d, ctl := startDaemon(t, burntest.NewClient("alice@example.com", ""), 0)
+	ctx := context.Background()
+	delivered, revoked := writeOfferFile(t, "top secret plans"), writeOfferFile(t, "draft")
+	add := func(path string) OfferStatus {
+		st, err := ctl.Add(ctx, OfferSpec{Paths: []string{path}, Target: "alice@example.com", Wipe: true, Expires: time.Now().Add(time.Minute)})
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		return st
+	}
+	st := add(delivered)
+	if _, err := Receive(ctx, st.Links[0].URL, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	ctl.Revoke(ctx, add(revoked).ID)
+	waitFor(t, func() bool { return len(d.List()) == 0 })
+
+	if _, err := os.Stat(delivered); !os.IsNotExist(err) {
+		t.Fatalf("expected the delivered file to be wiped, got %v", err)
+	}
+	if _, err := os.Stat(revoked); err != nil {
+		t.Fatalf("expected the revoked file to be kept: %v", err)
+	}
+}
+
+func TestDaemonIdle(t *testing.T) {
+	d, ctl := startDaemon(t, burntest.NewClient("alice@example.com", ""), 100*time.Millisecond)
+	st, err := ctl.Add(context.Background(), OfferSpec{Text: []byte("hunter2"), Target: "alice@example.com", Expires: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	idled := make(chan struct{})
+	go func() {
+		d.Idle(context.Background())
+		close(idled)
+	}()
+
+	// Not while it hosts an offer
+	select {
+	case <-idled:
+		t.Fatalf("expected the daemon to stay up while it hosts an offer")
+	case <-time.After(300 * time.Millisecond):
+	}
+	ctl.Revoke(context.Background(), st.ID)
+	select {
+	case <-idled:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the daemon to go idle once the offer burned")
+	}
+	if st, _ := ctl.Status(context.Background()); st.IdleSince.IsZero() {
+		t.Fatalf("expected an idle daemon to say since when, got %+v", st)
+	}
+}
+
+func TestListenControlRefusesRunningDaemon(t *testing.T) {
+	socket := controlSocket(t, "ctl.sock")
+	ln, err := ListenControl(socket)
+	if err != nil {
+		t.Fatalf("ListenControl: %v", err)
+	}
+	go (&Daemon{Server: &Server{}}).ServeControl(ln)
+	if _, err := ListenControl(socket); err == nil {
+		t.Fatalf("expected a second daemon on the same socket to be refused")
+	}
+	if fi, err := os.Stat(socket); err != nil || fi.Mode().Perm()&0077 != 0 {
+		t.Fatalf("expected a private socket, got %v (%v)", fi.Mode(), err)
+	}
+
+	// A socket left behind by a daemon that is gone is replaced
+	ln.Close()
+	stale := controlSocket(t, "stale.sock")
+	os.WriteFile(stale, nil, 0600)
+	ln, err = ListenControl(stale)
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced: %v", err)
+	}
+	ln.Close()
+}
+
+func TestListenControlRefusesOpenDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no mode bits on Windows")
+	}
+	dir := t.TempDir()
+	os.Chmod(dir, 0755)
+	if ln, err := ListenControl(filepath.Join(dir, "ctl.sock")); err == nil {
+		ln.Close()
+		t.Fatalf("expected a socket in a directory others can enter to be refused")
+	}
+
+	// A symlink to a private directory isn't one
+	link := filepath.Join(t.TempDir(), "link")
+	os.Symlink(filepath.Dir(controlSocket(t, "ctl.sock")), link)
+	if ln, err := ListenControl(filepath.Join(link, "ctl.sock")); err == nil {
+		ln.Close()
+		t.Fatalf("expected a socket behind a symlink to be refused")
+	}
+}
+
+// waitFor polls cond for up to two seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package burn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tail-burn/burn/burntest"
+)
+
+func TestReceiveVerifiesDigest(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	digest, err := fileDigest(filePath)
+	if err != nil {
+		t.Fatalf("fileDigest: %v", err)
+	}
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf"}))
+	dest := t.TempDir()
+	if _, err := Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{digest: digest}), ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "plans.pdf")); err != nil {
+		t.Fatalf("expected verified file: %v", err)
+	}
+	if f.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", f.acks.Load())
+	}
+}
+
+func TestReceiveDigestMismatchDeletesAndSkipsAck(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("tampered plans"), 0600)
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf"}))
+	dest := t.TempDir()
+	_, err := Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{digest: strings.Repeat("00", 32)}), ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "integrity check failed") {
+		t.Fatalf("expected integrity error, got %v", err)
+	}
+	entries, _ := os.ReadDir(dest)
+	if len(entries) != 0 {
+		t.Fatalf("expected no files left behind, got %v", entries)
+	}
+	if f.acks.Load() != 0 {
+		t.Fatalf("expected no ACK after mismatch, got %d", f.acks.Load())
+	}
+}
+
+func TestArchiveDigestMatchesServedStream(t *testing.T) {
+	src := makeTree(t)
+	digest, err := archiveDigest([]string{src}, writeTar)
+	if err != nil {
+		t.Fatalf("archiveDigest: %v", err)
+	}
+
+	f := newTestShare(t, withPayload(&payload{paths: []string{src}, name: "configs"}))
+	dest := t.TempDir()
+	if _, err := Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{digest: digest}), ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if f.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", f.acks.Load())
+	}
+}
+
+func TestLandingPageShowsDigest(t *testing.T) {
+	digest := strings.Repeat("cd", 32)
+	mux := http.NewServeMux()
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{"unused"}, name: "plans.pdf", size: 1024, browserDigest: digest},
+		&recipient{target: loginTarget("target@example.com"), secretPath: "/secret", done: make(chan string, 1)})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), digest) {
+		t.Fatalf("expected landing page to show the digest")
+	}
+}
@@ -0,0 +1,117 @@
+package burn
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"tailscale.com/client/tailscale/apitype"
+)
+
+// --- PER-RECIPIENT FAN-OUT ---
+// send -per-recipient turns every top-level -target term into its own offer
+// on the same node: its own secret path, ACK ledger and burn state. One
+// recipient collecting doesn't burn anyone else's link; the node shuts down
+// once all of them have collected (or at the deadline).
+
+// recipient is one identity's share of an offer.
+type recipient struct {
+	target     Authorizer
+	secretPath string
+	done       chan string // this share's shutdown signal
+	hosted     *Hosted     // what the server's callbacks are told about
+}
+
+func (r *recipient) ackPath() string { return r.secretPath + "/ack" }
+
+// recipientOffer pairs an offer's metadata with who may see it.
+type recipientOffer struct {
+	target Authorizer
+	info   OfferInfo
+}
+
+// offerFor returns the first offer who may see.
+func offerFor(offers []recipientOffer, who *apitype.WhoIsResponse) (OfferInfo, bool) {
+	for _, o := range offers {
+		if o.target.Allow(who) {
+			return o.info, true
+		}
+	}
+	return OfferInfo{}, false
+}
+
+// newSecretPath returns a random, unguessable URL path.
+func newSecretPath() (string, error) {
+	randBytes := make([]byte, 12)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", err
+	}
+	return "/" + hex.EncodeToString(randBytes), nil
+}
+
+// newRecipients gives every target its own share. With acl, each one also
+// needs a fitting grant.
+func newRecipients(targets []Authorizer, acl bool, size int64, class string) ([]*recipient, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("a per-recipient offer needs recipients")
+	}
+	var recipients []*recipient
+	for _, target := range targets {
+		if acl {
+			target = allOf{target, capTarget{size: size, class: class}}
+		}
+		secretPath, err := newSecretPath()
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &recipient{target: target, secretPath: secretPath, done: make(chan string, 1)})
+	}
+	return recipients, nil
+}
+
+// fanout tracks which recipients have collected.
+type fanout struct {
+	mu        sync.Mutex
+	collected map[*recipient]bool
+}
+
+// track waits for every recipient's share to finish, passing each one to
+// report, and signals shutdown once all have.
+func (f *fanout) track(recipients []*recipient, shutdownSignal chan string, report func(r *recipient, reason string)) {
+	f.mu.Lock()
+	f.collected = make(map[*recipient]bool)
+	f.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, r := range recipients {
+		wg.Go(func() {
+			reason := <-r.done
+			f.mu.Lock()
+			f.collected[r] = true
+			f.mu.Unlock()
+			report(r, reason)
+		})
+	}
+	go func() {
+		wg.Wait()
+		select {
+		case shutdownSignal <- ReasonCollected:
+		default:
+		}
+	}()
+}
+
+// status splits recipients into who has and hasn't collected.
+func (f *fanout) status(recipients []*recipient) (got, pending []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range recipients {
+		if f.collected[r] {
+			got = append(got, r.target.String())
+		} else {
+			pending = append(pending, r.target.String())
+		}
+	}
+	return got, pending
+}
@@ -0,0 +1,128 @@
+package burn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+func TestNewRecipients(t *testing.T) {
+	targets, err := ParseTargets("alice@example.com, all-of(tag:ci, node:build-01), bob@example.com")
+	if err != nil {
+		t.Fatalf("ParseTargets: %v", err)
+	}
+	recipients, err := newRecipients(targets, false, 10, ClassFile)
+	if err != nil {
+		t.Fatalf("newRecipients: %v", err)
+	}
+	if len(recipients) != 3 {
+		t.Fatalf("expected 3 recipients, got %d", len(recipients))
+	}
+	paths := map[string]bool{}
+	for _, r := range recipients {
+		paths[r.secretPath] = true
+	}
+	if len(paths) != 3 {
+		t.Fatalf("expected a distinct secret path per recipient")
+	}
+	if recipients[1].target.String() != "all-of(tag:ci, node:build-01)" {
+		t.Fatalf("unexpected term %s", recipients[1].target)
+	}
+
+	withACL, _ := newRecipients(targets[:1], true, 10, ClassFile)
+	if withACL[0].target.Allow(userPeer("alice@example.com")) {
+		t.Fatalf("expected -acl to require a grant per recipient")
+	}
+	if _, err := newRecipients(nil, true, 10, ClassFile); err == nil {
+		t.Fatalf("expected a per-recipient offer without recipients to fail")
+	}
+}
+
+func TestPerRecipientShares(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "bundle.tgz")
+	os.WriteFile(filePath, []byte("release bundle"), 0600)
+	targets, _ := ParseTargets("alice@example.com,bob@example.com")
+	recipients, _ := newRecipients(targets, false, 14, ClassFile)
+
+	shutdown := make(chan string, 1)
+	var shares fanout
+	collected := make(chan string, 2)
+	shares.track(recipients, shutdown, func(r *recipient, reason string) { collected <- r.target.String() })
+
+	// Each share sees its own identity; serve it to whoever asks for its path
+	mux := http.NewServeMux()
+	for i, r := range recipients {
+		login := []string{"alice@example.com", "bob@example.com"}[i]
+		serveShare(mux, burntest.NewClient(login, "sender@example.com"),
+			&payload{paths: []string{filePath}, name: "bundle.tgz", size: 14},
+			r)
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	alice := recipients[0]
+	if _, err := Receive(context.Background(), server.URL+alice.secretPath, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("alice: %v", err)
+	}
+	// Alice's link is burned, Bob's is untouched
+	for _, tt := range []struct {
+		path string
+		want int
+	}{{alice.secretPath, http.StatusGone}, {recipients[1].secretPath, http.StatusOK}} {
+		req, _ := http.NewRequest("HEAD", server.URL+tt.path, nil)
+		req.Header.Set("X-Tail-Burn-Client", "true")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Fatalf("HEAD %s: got %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+	}
+	select {
+	case reason := <-shutdown:
+		t.Fatalf("shut down before everyone collected: %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if who := <-collected; who != "alice@example.com" {
+		t.Fatalf("expected alice to be reported, got %s", who)
+	}
+	got, pending := shares.status(recipients)
+	if !slices.Equal(got, []string{"alice@example.com"}) || !slices.Equal(pending, []string{"bob@example.com"}) {
+		t.Fatalf("unexpected status: collected %v, pending %v", got, pending)
+	}
+
+	if _, err := Receive(context.Background(), server.URL+recipients[1].secretPath, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("bob: %v", err)
+	}
+	select {
+	case reason := <-shutdown:
+		if reason != "All recipients collected" {
+			t.Fatalf("unexpected shutdown reason %q", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected shutdown once everyone collected")
+	}
+}
+
+func TestOfferFor(t *testing.T) {
+	offers := []recipientOffer{
+		{target: loginTarget("alice@example.com"), info: OfferInfo{Link: "/a"}},
+		{target: loginTarget("bob@example.com"), info: OfferInfo{Link: "/b"}},
+	}
+	if o, ok := offerFor(offers, userPeer("bob@example.com")); !ok || o.Link != "/b" {
+		t.Fatalf("expected bob's offer, got %+v %v", o, ok)
+	}
+	if _, ok := offerFor(offers, userPeer("mallory@example.com")); ok {
+		t.Fatalf("expected no offer for mallory")
+	}
+}
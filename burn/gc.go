@@ -0,0 +1,79 @@
+package burn
+
+import (
+	"crypto/rand"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- CLEANUP & GC ---
+// An ephemeral node leaves a tsnet-tail-burn-* state directory (with its node
+// key) in the user config directory until its cleanup removes it. A crash or
+// SIGKILL skips cleanup and leaves one behind, which tail-burn gc removes.
+// Every ephemeral node marks its directory with its PID, so gc never touches
+// one whose process is still running.
+
+const stateMarker = "tail-burn.pid"
+
+// ephemeralStateDir matches the state directories NewEphemeralNode creates.
+var ephemeralStateDir = regexp.MustCompile(`^tsnet-tail-burn(-[0-9]+|-recv|-daemon)?-[0-9a-f]{4}$`)
+
+// markStateDir creates dir and records which process owns it.
+func markStateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, stateMarker), []byte(strconv.Itoa(os.Getpid())), 0600)
+}
+
+// StaleStateDirs lists the ephemeral state directories in configDir whose
+// owner is gone, for ShredDir. Unmarked ones (from older versions) count once they are
+// older than minAge.
+func StaleStateDirs(configDir string, minAge time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		return nil, err
+	}
+	var stale []string
+	for _, e := range entries {
+		if !e.IsDir() || !ephemeralStateDir.MatchString(e.Name()) {
+			continue
+		}
+		dir := filepath.Join(configDir, e.Name())
+		if pid, err := os.ReadFile(filepath.Join(dir, stateMarker)); err == nil {
+			if n, err := strconv.Atoi(strings.TrimSpace(string(pid))); err == nil && processAlive(n) {
+				continue
+			}
+		} else if info, err := e.Info(); err != nil || time.Since(info.ModTime()) < minAge {
+			continue
+		}
+		stale = append(stale, dir)
+	}
+	return stale, nil
+}
+
+// ShredDir overwrites every regular file in dir with random bytes before
+// removing it. Best effort: SSDs and copy-on-write filesystems may keep the
+// old blocks, but the node key no longer sits in a readable file.
+func ShredDir(dir string) error {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			if info, err := f.Stat(); err == nil {
+				io.CopyN(f, rand.Reader, info.Size())
+				f.Sync()
+			}
+			f.Close()
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,90 @@
+package burn
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// deadPID returns the PID of a process that has already exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running helper process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestStaleStateDirs(t *testing.T) {
+	config := t.TempDir()
+	mkdir := func(name string, pid int, age time.Duration) string {
+		dir := filepath.Join(config, name)
+		os.MkdirAll(dir, 0700)
+		os.WriteFile(filepath.Join(dir, "tailscaled.state"), []byte("node key"), 0600)
+		if pid != 0 {
+			os.WriteFile(filepath.Join(dir, stateMarker), []byte(strconv.Itoa(pid)), 0600)
+		}
+		old := time.Now().Add(-age)
+		os.Chtimes(dir, old, old)
+		return dir
+	}
+	crashed := mkdir("tsnet-tail-burn-7-a1b2", deadPID(t), 0)
+	legacy := mkdir("tsnet-tail-burn-recv-00ff", 0, 48*time.Hour)
+	daemon := mkdir("tsnet-tail-burn-daemon-ab12", deadPID(t), 0)
+	mkdir("tsnet-tail-burn-beef", os.Getpid(), 48*time.Hour) // still running
+	mkdir("tsnet-tail-burn-c0de", 0, time.Minute)            // unmarked, maybe still running
+	mkdir("tsnet-tail-burn-drop", 0, 48*time.Hour)           // a listener's own node
+	mkdir("tsnet-other-app-a1b2", 0, 48*time.Hour)
+
+	stale, err := StaleStateDirs(config, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("StaleStateDirs: %v", err)
+	}
+	slices.Sort(stale)
+	if want := []string{crashed, daemon, legacy}; !slices.Equal(stale, want) {
+		t.Fatalf("stale = %v, want %v", stale, want)
+	}
+}
+
+func TestShredDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tsnet-tail-burn-a1b2")
+	os.MkdirAll(filepath.Join(dir, "logs"), 0700)
+	os.WriteFile(filepath.Join(dir, "tailscaled.state"), []byte("node key"), 0600)
+	os.WriteFile(filepath.Join(dir, "logs", "tailscaled.log1.txt"), []byte("log"), 0600)
+
+	if err := ShredDir(dir); err != nil {
+		t.Fatalf("ShredDir: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be gone", dir)
+	}
+}
+
+func TestNewEphemeralNodeMarksState(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	s, cleanup, err := NewEphemeralNode("tail-burn", "tskey-test", false)
+	if err != nil {
+		t.Fatalf("NewEphemeralNode: %v", err)
+	}
+	pid, err := os.ReadFile(filepath.Join(s.Dir, stateMarker))
+	if err != nil || string(pid) != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("expected the state dir to be marked with our PID, got %q (%v)", pid, err)
+	}
+	if stale, _ := StaleStateDirs(filepath.Dir(s.Dir), 0); len(stale) != 0 {
+		t.Fatalf("expected a live node's state to be kept, got %v", stale)
+	}
+
+	// Never started: cleanup must not touch the node, only its state
+	cleanup()
+	cleanup()
+	if _, err := os.Stat(s.Dir); !os.IsNotExist(err) {
+		t.Fatalf("expected cleanup to remove %s", s.Dir)
+	}
+}
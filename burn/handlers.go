@@ -0,0 +1,802 @@
+package burn
+
+import (
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// --- HTML TEMPLATE (Browser Fallback with UI Fix) ---
+const htmlTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .file-info { background: #f4f4f5; padding: 15px; border-radius: 8px; margin-bottom: 25px; font-family: monospace; font-size: 14px; text-align: left; }
+        .digest { margin-top: 8px; font-size: 11px; word-break: break-all; color: #52525b; }
+        .btn { background: #ef4444; color: white; border: none; padding: 12px 24px; border-radius: 6px; font-size: 16px; font-weight: 600; cursor: pointer; width: 100%; transition: background 0.2s; }
+        .btn:hover { background: #dc2626; }
+        .btn:disabled { background: #a1a1aa; cursor: not-allowed; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
+        .hidden { display: none; }
+        .error { color: #dc2626; margin: 15px 0 0; font-size: 14px; }
+        progress { width: 100%; margin: 15px 0 0; }
+        .progress-text { margin-top: 6px; font-size: 12px; color: #52525b; }
+    </style>
+    <script>
+        var encrypted = {{.Encrypted}};
+        var saveAs = {{.SaveAs}};
+        var ackPath = {{.AckPath}};
+        var wantDigest = {{.Digest}};
+        var CHUNK = 65536, TAG = 16;
+
+        // Scripted download: fetch, verify, save, then ACK. Browsers that
+        // can't stream a fetch submit the form instead and the sender
+        // guesses when the download finished.
+        function onDownload() {
+            if (window.fetch && window.ReadableStream && window.Uint8Array) {
+                download();
+                return false;
+            }
+            if (encrypted) {
+                showError('This browser cannot decrypt the download. Use "tail-burn receive" instead.');
+                return false;
+            }
+            triggerBurn();
+            return true;
+        }
+
+        function showDone(digest) {
+            document.getElementById('doneDigest').innerText = '🔒 SHA-256 ' + digest;
+            document.getElementById('mainContent').classList.add('hidden');
+            document.getElementById('doneState').classList.remove('hidden');
+        }
+
+        function showError(msg) {
+            var err = document.getElementById('error');
+            err.innerText = msg;
+            err.classList.remove('hidden');
+        }
+
+        function showProgress(got, total) {
+            var bar = document.getElementById('progress');
+            bar.classList.remove('hidden');
+            if (total) {
+                bar.value = got / total;
+            } else {
+                bar.removeAttribute('value');
+            }
+            document.getElementById('progressText').innerText = FormatBytes(got) + (total ? ' of ' + FormatBytes(total) : '');
+        }
+
+        function FormatBytes(n) {
+            var units = ['B', 'KB', 'MB', 'GB', 'TB'], i = 0;
+            while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+            return (i ? n.toFixed(1) : n) + ' ' + units[i];
+        }
+
+        function fromBase64URL(s) {
+            s = s.replace(/-/g, '+').replace(/_/g, '/');
+            while (s.length % 4) s += '=';
+            var bin = atob(s), out = new Uint8Array(bin.length);
+            for (var i = 0; i < bin.length; i++) out[i] = bin.charCodeAt(i);
+            return out;
+        }
+
+        function fromHex(s) {
+            var out = new Uint8Array(s.length / 2);
+            for (var i = 0; i < out.length; i++) out[i] = parseInt(s.substr(i * 2, 2), 16);
+            return out;
+        }
+
+        function concat(a, b) {
+            var c = new Uint8Array(a.length + b.length);
+            c.set(a);
+            c.set(b, a.length);
+            return c;
+        }
+
+        // Incremental SHA-256: WebCrypto can only hash a whole buffer, and
+        // the download may be far larger than memory
+        var SHA256_K = new Uint32Array([
+            0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+            0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+            0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+            0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+            0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+            0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+            0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+            0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2]);
+
+        function Sha256() {
+            this.h = new Uint32Array([0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19]);
+            this.w = new Uint32Array(64);
+            this.buf = new Uint8Array(64);
+            this.len = 0;
+            this.total = 0;
+        }
+
+        Sha256.prototype.block = function(p, off) {
+            var w = this.w, h = this.h, i;
+            for (i = 0; i < 16; i++, off += 4) w[i] = p[off] << 24 | p[off + 1] << 16 | p[off + 2] << 8 | p[off + 3];
+            for (i = 16; i < 64; i++) {
+                var x = w[i - 15], y = w[i - 2];
+                w[i] = w[i - 16] + ((x >>> 7 | x << 25) ^ (x >>> 18 | x << 14) ^ (x >>> 3)) +
+                    w[i - 7] + ((y >>> 17 | y << 15) ^ (y >>> 19 | y << 13) ^ (y >>> 10));
+            }
+            var a = h[0], b = h[1], c = h[2], d = h[3], e = h[4], f = h[5], g = h[6], k = h[7];
+            for (i = 0; i < 64; i++) {
+                var t1 = (k + ((e >>> 6 | e << 26) ^ (e >>> 11 | e << 21) ^ (e >>> 25 | e << 7)) + ((e & f) ^ (~e & g)) + SHA256_K[i] + w[i]) | 0;
+                var t2 = (((a >>> 2 | a << 30) ^ (a >>> 13 | a << 19) ^ (a >>> 22 | a << 10)) + ((a & b) ^ (a & c) ^ (b & c))) | 0;
+                k = g; g = f; f = e; e = (d + t1) | 0; d = c; c = b; b = a; a = (t1 + t2) | 0;
+            }
+            h[0] += a; h[1] += b; h[2] += c; h[3] += d; h[4] += e; h[5] += f; h[6] += g; h[7] += k;
+        };
+
+        Sha256.prototype.update = function(data) {
+            var i = 0;
+            this.total += data.length;
+            if (this.len) {
+                i = Math.min(64 - this.len, data.length);
+                this.buf.set(data.subarray(0, i), this.len);
+                this.len += i;
+                if (this.len < 64) return;
+                this.block(this.buf, 0);
+                this.len = 0;
+            }
+            for (; i + 64 <= data.length; i += 64) this.block(data, i);
+            this.buf.set(data.subarray(i));
+            this.len = data.length - i;
+        };
+
+        Sha256.prototype.hex = function() {
+            var bits = this.total * 8;
+            var pad = new Uint8Array((this.len < 56 ? 64 : 128) - this.len), v = new DataView(pad.buffer);
+            pad[0] = 0x80;
+            v.setUint32(pad.length - 8, Math.floor(bits / 4294967296));
+            v.setUint32(pad.length - 4, bits >>> 0);
+            this.update(pad);
+            var out = '';
+            for (var i = 0; i < 8; i++) out += ('0000000' + this.h[i].toString(16)).slice(-8);
+            return out;
+        };
+
+        // Mirrors chunkNonce in crypto.go: 11-byte counter || last flag
+        function chunkNonce(i, last) {
+            var n = new Uint8Array(12), v = new DataView(n.buffer);
+            v.setUint32(3, Math.floor(i / 4294967296));
+            v.setUint32(7, i >>> 0);
+            n[11] = last ? 1 : 0;
+            return n;
+        }
+
+        async function openChunk(key, i, last, data) {
+            return new Uint8Array(await crypto.subtle.decrypt({ name: 'AES-GCM', iv: chunkNonce(i, last) }, key, data));
+        }
+
+        // decrypter returns the plaintext that is complete so far; the key in
+        // the #fragment never leaves the browser
+        async function decrypter(resp) {
+            var params = new URLSearchParams(location.hash.slice(1));
+            var base = await crypto.subtle.importKey('raw', fromBase64URL(params.get('key')), 'HKDF', false, ['deriveKey']);
+            var key = await crypto.subtle.deriveKey(
+                { name: 'HKDF', hash: 'SHA-256', salt: fromHex(resp.headers.get('X-Tail-Burn-Salt') || ''), info: new TextEncoder().encode('tail-burn/v1') },
+                base, { name: 'AES-GCM', length: 256 }, false, ['decrypt']);
+            var pending = new Uint8Array(0), i = 0;
+            return async function(data, end) {
+                var out = [];
+                pending = concat(pending, data);
+                // A full-size chunk is never the last one
+                while (pending.length >= CHUNK + TAG) {
+                    out.push(await openChunk(key, i++, false, pending.subarray(0, CHUNK + TAG)));
+                    pending = pending.slice(CHUNK + TAG);
+                }
+                if (end) {
+                    if (pending.length < TAG) throw new Error('stream truncated');
+                    out.push(await openChunk(key, i, true, pending));
+                }
+                return out;
+            };
+        }
+
+        // saver writes to a file picked up front (File System Access API,
+        // where writes only land on close) or collects a Blob.
+        async function saver() {
+            if (window.showSaveFilePicker) {
+                try {
+                    var handle = await showSaveFilePicker({ suggestedName: saveAs });
+                    var file = await handle.createWritable();
+                    return {
+                        write: function(b) { return file.write(b); },
+                        close: function() { return file.close(); },
+                        abort: function() { return file.abort(); }
+                    };
+                } catch (e) {
+                    if (e.name === 'AbortError') return null;
+                }
+            }
+            var parts = [];
+            return {
+                write: function(b) { parts.push(b); },
+                close: function() {
+                    var url = URL.createObjectURL(new Blob(parts));
+                    var a = document.createElement('a');
+                    a.href = url;
+                    a.download = saveAs;
+                    document.body.appendChild(a);
+                    a.click();
+                    setTimeout(function() { URL.revokeObjectURL(url); }, 60000);
+                },
+                abort: function() { parts = []; }
+            };
+        }
+
+        async function download() {
+            var btn = document.getElementById('dlBtn');
+            document.getElementById('error').classList.add('hidden');
+            if (encrypted && !new URLSearchParams(location.hash.slice(1)).get('key')) {
+                showError('This link is missing its decryption key. Ask the sender for the full link.');
+                return;
+            }
+            if (encrypted && (!window.crypto || !crypto.subtle)) {
+                showError('Decrypting in the browser needs HTTPS. Use "tail-burn receive" instead.');
+                return;
+            }
+            // Ask where to save first: the picker needs this click
+            var out = await saver();
+            if (!out) return;
+
+            btn.disabled = true;
+            btn.innerText = "Downloading...";
+            try {
+                var resp = await fetch(location.pathname, { method: 'POST', headers: { 'X-Tail-Burn-Browser-Ack': 'true' } });
+                if (resp.status === 410) throw new Error('this link has already been used');
+                if (!resp.ok) throw new Error('HTTP ' + resp.status);
+                var decrypt = encrypted ? await decrypter(resp) : null;
+                var total = Number(resp.headers.get('Content-Length')) || 0;
+                var reader = resp.body.getReader(), hash = new Sha256(), got = 0;
+                var emit = async function(chunks) {
+                    for (var j = 0; j < chunks.length; j++) {
+                        hash.update(chunks[j]);
+                        await out.write(chunks[j]);
+                    }
+                };
+                for (;;) {
+                    var r = await reader.read();
+                    if (r.done) break;
+                    got += r.value.length;
+                    await emit(decrypt ? await decrypt(r.value, false) : [r.value]);
+                    showProgress(got, total);
+                }
+                if (decrypt) await emit(await decrypt(new Uint8Array(0), true));
+                if (total && got !== total) throw new Error('download incomplete');
+
+                var digest = hash.hex();
+                if (wantDigest && digest !== wantDigest) throw new Error('integrity check failed: SHA-256 ' + digest);
+                await out.close();
+                out = null;
+
+                // Only now tell the sender it can burn the link
+                btn.innerText = "Confirming...";
+                var ack = await fetch(ackPath, {
+                    method: 'POST',
+                    headers: { 'X-Tail-Burn-Token': resp.headers.get('X-Tail-Burn-Token') || '', 'X-Tail-Burn-SHA256': digest }
+                });
+                if (!ack.ok) throw new Error('the sender rejected the confirmation (HTTP ' + ack.status + ')');
+                showDone(digest);
+            } catch (e) {
+                if (out) {
+                    try { await out.abort(); } catch (ignored) {}
+                }
+                btn.disabled = false;
+                btn.innerText = "Download & Destroy";
+                showError('Download failed: ' + (e.message || 'could not decrypt'));
+            }
+        }
+
+        function triggerBurn() {
+            var btn = document.getElementById('dlBtn');
+            var card = document.getElementById('mainContent');
+            var done = document.getElementById('doneState');
+            
+            // 1. Disable button immediately
+            btn.disabled = true;
+            btn.innerText = "Downloading...";
+            
+            // 2. Wait 1 second (ensure POST submits), then show Done state
+            setTimeout(function() {
+                card.classList.add('hidden');
+                done.classList.remove('hidden');
+            }, 1000);
+        }
+    </script>
+    {{template "countdown"}}
+</head>
+<body>
+    <div class="card">
+        <div id="mainContent">
+            <h1>🔥 Secure Drop</h1>
+            <p><b>{{.Sender}}</b> sent a file.</p>
+            <div class="file-info">
+                <div>📄 <b>{{.FileName}}</b></div>
+                <div>📦 <b>{{.FileSize}}</b></div>
+                {{if .Digest}}<div class="digest">🔒 SHA-256 <b>{{.Digest}}</b></div>{{end}}
+                {{if .Encrypted}}<div class="digest">🔐 End-to-end encrypted, decrypted in this page</div>{{end}}
+                {{if .Expires}}<div>⏳ Burns in <b data-until="{{.Expires}}">{{.Expires}}</b></div>{{end}}
+            </div>
+            <form method="POST" onsubmit="return onDownload()">
+                <button id="dlBtn" type="submit" class="btn">Download & Destroy</button>
+            </form>
+            <progress id="progress" class="hidden" max="1"></progress>
+            <div id="progressText" class="progress-text"></div>
+            <p id="error" class="error hidden"></p>
+            <div class="footer">⚠️ One-time use link.</div>
+        </div>
+
+        <div id="doneState" class="hidden">
+            <span class="success-icon">💥</span>
+            <h1>File Burned</h1>
+            <p>The file has been downloaded and the server is self-destructing.</p>
+            <div id="doneDigest" class="digest"></div>
+            <div class="footer">You may close this tab.</div>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+// --- HTML TEMPLATE (Burned Link) ---
+const burnedHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+    </style>
+</head>
+<body>
+    <div class="card">
+        <span class="success-icon">💥</span>
+        <h1>Link Burned</h1>
+        <p>This link has already been used or is no longer available.</p>
+        <div class="footer">Please request a new link.</div>
+    </div>
+</body>
+</html>
+`
+
+// --- HTML TEMPLATE (One-Time Secret) ---
+const secretHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <meta name="referrer" content="no-referrer">
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .secret { background: #f4f4f5; padding: 15px; border-radius: 8px; margin-bottom: 25px; font-family: monospace; font-size: 14px; text-align: left; white-space: pre-wrap; word-break: break-all; max-height: 300px; overflow: auto; }
+        .btn { background: #ef4444; color: white; border: none; padding: 12px 24px; border-radius: 6px; font-size: 16px; font-weight: 600; cursor: pointer; width: 100%; transition: background 0.2s; }
+        .btn:hover { background: #dc2626; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+    </style>
+    <script>
+        function copySecret() {
+            var btn = document.getElementById('copyBtn');
+            navigator.clipboard.writeText(document.getElementById('secret').textContent).then(function() {
+                btn.innerText = "Copied ✓";
+            }, function() {
+                btn.innerText = "Copy failed, select the text instead";
+            });
+        }
+    </script>
+</head>
+<body>
+    <div class="card">
+        {{if .Revealed}}
+        <h1>🔑 Secret</h1>
+        <p>This is the only time it will be shown.</p>
+        <pre id="secret" class="secret">{{.Secret}}</pre>
+        <button id="copyBtn" type="button" class="btn" onclick="copySecret()">Copy to Clipboard</button>
+        <div class="footer">💥 Burned. Reloading this page will not show it again.</div>
+        {{else}}
+        <h1>🔥 Secret Drop</h1>
+        <p><b>{{.Sender}}</b> sent you a secret ({{.Size}}).</p>
+        <form method="POST">
+            <button type="submit" class="btn">Reveal & Destroy</button>
+        </form>
+        <div class="footer">⚠️ It can only be revealed once.</div>
+        {{end}}
+    </div>
+</body>
+</html>
+`
+
+// --- HTML TEMPLATE (Upload Request) ---
+const uploadHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .file-info { background: #f4f4f5; padding: 15px; border-radius: 8px; margin-bottom: 25px; font-family: monospace; font-size: 14px; text-align: left; }
+        .btn { background: #ef4444; color: white; border: none; padding: 12px 24px; border-radius: 6px; font-size: 16px; font-weight: 600; cursor: pointer; width: 100%; transition: background 0.2s; }
+        .btn:hover { background: #dc2626; }
+        .btn:disabled { background: #a1a1aa; cursor: not-allowed; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+    </style>
+    <script>
+        function onUpload() {
+            var btn = document.getElementById('upBtn');
+            btn.disabled = true;
+            btn.innerText = "Uploading...";
+            return true;
+        }
+    </script>
+</head>
+<body>
+    <div class="card">
+        <h1>🔥 Secure Drop</h1>
+        <p><b>{{.Requester}}</b> is asking you for a file.</p>
+        <form method="POST" enctype="multipart/form-data" onsubmit="return onUpload()">
+            <div class="file-info"><input type="file" name="file" required></div>
+            <button id="upBtn" type="submit" class="btn">Upload & Destroy</button>
+        </form>
+        <div class="footer">⚠️ One-time use link. Only one file can be sent.</div>
+    </div>
+</body>
+</html>
+`
+
+// --- HTML TEMPLATE (Upload Received) ---
+const uploadedHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+    </style>
+</head>
+<body>
+    <div class="card">
+        <span class="success-icon">💥</span>
+        <h1>File Delivered</h1>
+        <p>The file was received and the link is self-destructing.</p>
+        <div class="footer">You may close this tab.</div>
+    </div>
+</body>
+</html>
+`
+
+var landingTemplate = template.Must(template.Must(template.New("landing").Parse(htmlTemplate)).Parse(countdownHTMLTemplate))
+var burnedTemplate = template.Must(template.New("burned").Parse(burnedHTMLTemplate))
+var secretTemplate = template.Must(template.New("secret").Parse(secretHTMLTemplate))
+var uploadTemplate = template.Must(template.New("upload").Parse(uploadHTMLTemplate))
+var uploadedTemplate = template.Must(template.New("uploaded").Parse(uploadedHTMLTemplate))
+var browserShutdownDelay = 5 * time.Second
+
+// landingData fills htmlTemplate.
+type landingData struct {
+	Sender, FileName, FileSize, Digest string
+	Encrypted                          bool
+	SaveAs                             string // name the in-page download saves under
+	AckPath                            string // where the page confirms a verified save
+	Expires                            string // RFC 3339, from the schedule guard
+}
+
+// browserFileName is what a browser saves the payload as.
+func browserFileName(fileName string, archive bool) string {
+	if archive {
+		return fileName + ".zip"
+	}
+	return fileName
+}
+
+// registerHandlers serves one share of a file, archive or stream offer: the
+// download at its secret path and the kill switch at its ACK path.
+func (s *Server) registerHandlers(mux *http.ServeMux, p *payload, share *recipient) {
+	var used atomic.Bool
+	var inProgress atomic.Bool
+	var served peerCoverage
+	var acks ackLedger
+	var drained atomic.Bool // a stream has been (or is being) read
+	archive := p.stream == nil && isArchivePayload(p.paths)
+
+	// 1. The ACK Handler (Smart Client Kill Switch)
+	mux.HandleFunc(share.ackPath(), s.ackHandler(share, &acks, &used, nil))
+
+	// 2. The Main Handler (Download)
+	mux.HandleFunc(share.secretPath, func(w http.ResponseWriter, r *http.Request) {
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		if !share.target.Allow(who) {
+			s.blocked(share.hosted, who)
+			http.Error(w, "Forbidden", 403)
+			return
+		}
+
+		// Detect if it's our smart client
+		isSmartClient := r.Header.Get("X-Tail-Burn-Client") == "true"
+		// The landing page's script ACKs like the smart client does; only
+		// the no-JS form POST leaves us guessing
+		willAck := isSmartClient || r.Header.Get(browserAckHeader) == "true"
+
+		if used.Load() {
+			if !isSmartClient {
+				w.WriteHeader(http.StatusGone)
+				_ = burnedTemplate.Execute(w, nil)
+				return
+			}
+			http.Error(w, "Gone", http.StatusGone)
+			return
+		}
+
+		// Smart client probing a download before resuming it
+		if r.Method == "HEAD" && isSmartClient {
+			if p.key != nil {
+				w.Header().Set(encHeader, encScheme)
+			}
+			if p.stream != nil {
+				// No ETag: a stream can't be resumed
+				w.Header().Set(streamHeader, "true")
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", p.name))
+				return
+			}
+			if archive {
+				w.Header().Set("X-Tail-Burn-Archive", "tar")
+				return
+			}
+			fi, err := os.Stat(p.paths[0])
+			if err != nil {
+				http.Error(w, "File Error", http.StatusInternalServerError)
+				return
+			}
+			setFileHeaders(w, p.name, fi)
+			size := fi.Size()
+			if p.key != nil {
+				size = ciphertextSize(size)
+			}
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+			return
+		}
+
+		// A browser resuming an interrupted download sends a ranged GET
+		isRanged := r.Header.Get("Range") != ""
+
+		if r.Method == "GET" && !isSmartClient && !isRanged {
+			// Browser: Show HTML
+			sender := "A Tailscale User"
+			st, err := s.Client.Status(r.Context())
+			if err == nil && st != nil && st.Self != nil {
+				myUserID := st.Self.UserID
+				if profile, ok := st.User[myUserID]; ok {
+					sender = profile.LoginName
+				}
+			}
+
+			if err := landingTemplate.Execute(w, landingData{
+				Sender:    sender,
+				FileName:  p.name,
+				FileSize:  p.displaySize(),
+				Digest:    p.browserDigest,
+				Encrypted: p.key != nil,
+				SaveAs:    browserFileName(p.name, archive),
+				AckPath:   share.ackPath(),
+				Expires:   w.Header().Get(expiresHeader),
+			}); err != nil {
+				http.Error(w, "Template Error", http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		if r.Method == "POST" || (r.Method == "GET" && (isSmartClient || isRanged)) {
+			if !inProgress.CompareAndSwap(false, true) {
+				if !isSmartClient {
+					w.WriteHeader(http.StatusGone)
+					_ = burnedTemplate.Execute(w, nil)
+					return
+				}
+				http.Error(w, "Gone", http.StatusGone)
+				return
+			}
+			success := false
+			defer func() {
+				if !success {
+					inProgress.Store(false)
+				}
+			}()
+
+			s.logf("🚀 Sending file to %s...", peerName(who))
+			s.transferStarted(share.hosted, who)
+
+			// The token proves this transfer happened when the client ACKs
+			token, err := acks.issue(peerID(who))
+			if err != nil {
+				http.Error(w, "Token Error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(tokenHeader, token)
+			if p.key != nil {
+				w.Header().Set(encHeader, encScheme)
+			}
+			// What this transfer's ACK must report, if it reports anything
+			sentDigest := p.linkDigest
+			if !isSmartClient {
+				sentDigest = p.browserDigest
+			}
+
+			if p.stream != nil {
+				// One shot: whatever happens now, stdin is spent
+				if !drained.CompareAndSwap(false, true) {
+					http.Error(w, "Gone", http.StatusGone)
+					return
+				}
+				out, finishProgress := s.trackProgress(w, share.hosted, who, -1)
+				n, digest, err := serveStream(out, p.stream, p.name, p.key, isSmartClient)
+				finishProgress()
+				if err != nil {
+					s.logf("❌ Transfer failed: %v", err)
+					select {
+					case share.done <- ReasonStream:
+					default:
+					}
+					panic(http.ErrAbortHandler)
+				}
+				s.logf("🔒 Streamed %s, SHA-256 %s", FormatBytes(n), digest)
+				acks.finish(token, digest)
+			} else if archive {
+				// Archives are built on the fly, so there is no Content-Length;
+				// the response is chunked and a broken stream never terminates cleanly.
+				var writeArchive func(io.Writer, []string) error
+				if isSmartClient {
+					w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.tar\"", p.name))
+					w.Header().Set("Content-Type", "application/x-tar")
+					w.Header().Set("X-Tail-Burn-Archive", "tar")
+					writeArchive = writeTar
+				} else {
+					w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", browserFileName(p.name, true)))
+					w.Header().Set("Content-Type", "application/zip")
+					writeArchive = writeZip
+				}
+
+				pw, finishProgress := s.trackProgress(w, share.hosted, who, -1)
+				var out io.Writer = pw
+				var enc *encryptWriter
+				if p.key != nil {
+					// Archives are never ranged, so every stream gets a fresh salt
+					salt, err := randomSalt()
+					if err == nil {
+						var aead cipher.AEAD
+						if aead, err = streamAEAD(p.key, salt); err == nil {
+							enc = newEncryptWriter(pw, aead)
+							out = enc
+							w.Header().Set(encSaltHeader, hex.EncodeToString(salt))
+						}
+					}
+					if err != nil {
+						http.Error(w, "Encryption Error", http.StatusInternalServerError)
+						return
+					}
+				}
+				err := writeArchive(out, p.paths)
+				if err == nil && enc != nil {
+					err = enc.Close()
+				}
+				finishProgress()
+				if err != nil {
+					s.logf("❌ Transfer failed: %v", err)
+					panic(http.ErrAbortHandler)
+				}
+				acks.finish(token, sentDigest)
+			} else {
+				// Open file fresh for every request
+				file, err := os.Open(p.paths[0])
+				if err != nil {
+					http.Error(w, "File Error", http.StatusInternalServerError)
+					return
+				}
+				defer file.Close()
+
+				fi, err := file.Stat()
+				if err != nil {
+					http.Error(w, "File Error", http.StatusInternalServerError)
+					return
+				}
+				setFileHeaders(w, p.name, fi)
+				etag := fileETag(fi)
+
+				var content io.ReadSeeker = file
+				size := fi.Size()
+				if p.key != nil {
+					// Deterministic per offer and file version, so ranges line up across requests
+					salt := fileSalt(p.salt, etag)
+					aead, err := streamAEAD(p.key, salt)
+					if err != nil {
+						http.Error(w, "Encryption Error", http.StatusInternalServerError)
+						return
+					}
+					content = newEncryptedFile(file, fi.Size(), aead)
+					size = ciphertextSize(fi.Size())
+					w.Header().Set(encSaltHeader, hex.EncodeToString(salt))
+					if !isSmartClient {
+						w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.enc\"", p.name))
+					}
+				}
+
+				// Only bytes served to this identity count toward its download
+				cov := served.of(peerID(who))
+				pw, finishProgress := s.trackProgress(w, share.hosted, who, size)
+				err = serveFileRange(pw, r, content, size, etag, fi.ModTime(), cov)
+				finishProgress()
+				if err != nil {
+					s.logf("❌ Transfer failed: %v", err)
+					return
+				}
+				if !cov.complete(etag, size) {
+					// Only part of the file has gone out so far; keep the link alive
+					inProgress.Store(false)
+					success = true
+					s.logf("🧩 Partial transfer (%s of %s served to %s).", FormatBytes(cov.served(etag)), FormatBytes(size), peerName(who))
+					return
+				}
+				acks.finish(token, sentDigest)
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			inProgress.Store(false)
+			success = true
+
+			// A form POST without the script: we have to guess when to shut down
+			if !willAck {
+				used.Store(true)
+				s.logf("🔥 Browser transfer complete. Starting timer...")
+				delay := browserShutdownDelay
+				go func() {
+					time.Sleep(delay)
+					select {
+					case share.done <- ReasonBrowser:
+					default:
+					}
+				}()
+			}
+			// Otherwise we do NOTHING here. We wait for the /ack POST.
+		}
+	})
+}
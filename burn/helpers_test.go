@@ -0,0 +1,154 @@
+package burn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"tail-burn/burn/burntest"
+)
+
+// testShare is one offer (or upload request, inbox or push endpoint) served
+// over httptest, with a record of what clients did to it.
+type testShare struct {
+	server   *httptest.Server
+	client   *burntest.Client // who callers are; defaults to the recipient
+	shutdown chan string      // the recipient's done channel
+	acks     atomic.Int32     // POSTs to the ACK path
+
+	mu     sync.Mutex
+	ranges []string // the Range header of every download
+
+	drops chan error // push: the outcome of each drop
+	saved []string   // push: what the last good drop saved
+}
+
+// shareConfig is what newTestShare serves; shareOptions fill it in.
+type shareConfig struct {
+	payload   *payload
+	recipient *recipient
+	uploadDir string
+	inbox     []recipientOffer
+	pushAllow string
+	pushDest  string
+	wrap      func(http.Handler) http.Handler
+}
+
+type shareOption func(*shareConfig)
+
+// withPayload offers p: a secret through the text handlers, anything else
+// through the download handlers.
+func withPayload(p *payload) shareOption { return func(c *shareConfig) { c.payload = p } }
+
+// withRecipient offers to r instead of target@example.com. An empty
+// secretPath or done channel gets the default.
+func withRecipient(r *recipient) shareOption { return func(c *shareConfig) { c.recipient = r } }
+
+// withUploads serves an upload request saving into dir.
+func withUploads(dir string) shareOption { return func(c *shareConfig) { c.uploadDir = dir } }
+
+// withInbox lists offers on the inbox path.
+func withInbox(offers ...recipientOffer) shareOption {
+	return func(c *shareConfig) { c.inbox = append(c.inbox, offers...) }
+}
+
+// withPush runs a push endpoint for allow that receives each drop into dest
+// like the listen command does.
+func withPush(allow, dest string) shareOption {
+	return func(c *shareConfig) { c.pushAllow, c.pushDest = allow, dest }
+}
+
+// behind puts wrap in front of the handlers.
+func behind(wrap func(http.Handler) http.Handler) shareOption {
+	return func(c *shareConfig) { c.wrap = wrap }
+}
+
+// newTestShare serves what opts describe to a sender@example.com node.
+func newTestShare(t *testing.T, opts ...shareOption) *testShare {
+	t.Helper()
+	var c shareConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	share := c.recipient
+	if share == nil {
+		share = &recipient{target: loginTarget("target@example.com")}
+	}
+	if share.secretPath == "" {
+		share.secretPath = "/secret"
+	}
+	if share.done == nil {
+		share.done = make(chan string, 1)
+	}
+	login, _ := share.target.(loginTarget)
+
+	f := &testShare{
+		client:   burntest.NewClient(string(login), "sender@example.com"),
+		shutdown: share.done,
+		drops:    make(chan error, 1),
+	}
+	s := &Server{Client: f.client}
+	mux := http.NewServeMux()
+	switch {
+	case c.payload != nil && c.payload.secret != nil:
+		s.registerTextHandlers(mux, c.payload, share)
+	case c.payload != nil:
+		s.registerHandlers(mux, c.payload, share)
+	case c.uploadDir != "":
+		s.registerUploadHandlers(mux, c.uploadDir, share)
+	}
+	if c.inbox != nil {
+		s.registerInboxHandler(mux, func() []recipientOffer { return c.inbox })
+	}
+	if c.pushDest != "" {
+		auth, err := ParseTarget(c.pushAllow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.registerPushHandler(mux, auth, f.receiveDrop(c.pushDest))
+	}
+
+	var h http.Handler = mux
+	if c.wrap != nil {
+		h = c.wrap(h)
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == share.secretPath:
+			f.mu.Lock()
+			f.ranges = append(f.ranges, r.Header.Get("Range"))
+			f.mu.Unlock()
+		case r.URL.Path == share.ackPath():
+			f.acks.Add(1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// receiveDrop fetches a pushed link from the node that pushed it.
+func (f *testShare) receiveDrop(dest string) func(from Peer, link string) {
+	return func(from Peer, link string) {
+		res, err := Receive(context.Background(), link, ReceiveOptions{
+			Dir:    dest,
+			Stall:  DefaultStallPolicy,
+			From:   SenderCheck{Node: from.NodeID},
+			Client: f.client,
+		})
+		if err == nil {
+			f.saved = res.Paths
+		}
+		f.drops <- err
+	}
+}
+
+// downloads returns the Range header of every download so far.
+func (f *testShare) downloads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
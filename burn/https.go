@@ -0,0 +1,67 @@
+package burn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tsnet"
+)
+
+// --- HTTPS ---
+// Offers are served over HTTPS with the node's MagicDNS certificate, so
+// browsers don't warn and receivers can check the certificate as well as the
+// WireGuard identity. Port 80 only redirects. Tailnets without HTTPS
+// certificates fall back to plain HTTP (still inside WireGuard).
+
+// offerHost picks the name the link points at and whether it can use TLS.
+func offerHost(st *ipnstate.Status, certDomains []string, hostname string) (host string, useTLS bool) {
+	if len(certDomains) > 0 {
+		return certDomains[0], true
+	}
+	if st != nil && st.Self != nil && st.Self.DNSName != "" {
+		return strings.TrimSuffix(st.Self.DNSName, "."), false
+	}
+	return hostname, false
+}
+
+// httpsRedirect sends plain-HTTP requests to the same path on https://host.
+// 308 keeps the method, so the landing page form and ACKs survive it.
+func httpsRedirect(host string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// listenTailnet waits for the node to join (so its MagicDNS name and
+// certificate are known) and listens for the offer. base is scheme://host;
+// redirect, if not nil, is the port 80 server to shut down afterwards.
+func listenTailnet(ctx context.Context, s *tsnet.Server, hostname string, logf func(string, ...any)) (ln net.Listener, redirect *http.Server, base string, err error) {
+	st, err := s.Up(ctx)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("tailscale node did not come up: %w", err)
+	}
+	host, useTLS := offerHost(st, s.CertDomains(), hostname)
+
+	if !useTLS {
+		logf("⚠️  HTTPS certificates are not enabled for this tailnet; serving plain HTTP inside WireGuard.")
+		ln, err = s.Listen("tcp", ":80")
+		return ln, nil, "http://" + host, err
+	}
+	if ln, err = s.ListenTLS("tcp", ":443"); err != nil {
+		return nil, nil, "", err
+	}
+	// Browsers that drop the scheme land on :80; send them to HTTPS
+	httpLn, err := s.Listen("tcp", ":80")
+	if err != nil {
+		ln.Close()
+		return nil, nil, "", err
+	}
+	redirect = &http.Server{Handler: httpsRedirect(host), ReadHeaderTimeout: 5 * time.Second}
+	go redirect.Serve(httpLn)
+	return ln, redirect, "https://" + host, nil
+}
@@ -0,0 +1,41 @@
+package burn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tailscale.com/ipn/ipnstate"
+)
+
+func TestOfferHost(t *testing.T) {
+	st := &ipnstate.Status{Self: &ipnstate.PeerStatus{DNSName: "tail-burn-ab12.tailnet.ts.net."}}
+
+	host, useTLS := offerHost(st, []string{"tail-burn-ab12.tailnet.ts.net"}, "tail-burn-ab12")
+	if host != "tail-burn-ab12.tailnet.ts.net" || !useTLS {
+		t.Fatalf("with certs: got %q tls=%v", host, useTLS)
+	}
+
+	host, useTLS = offerHost(st, nil, "tail-burn-ab12")
+	if host != "tail-burn-ab12.tailnet.ts.net" || useTLS {
+		t.Fatalf("without certs: got %q tls=%v", host, useTLS)
+	}
+
+	host, useTLS = offerHost(&ipnstate.Status{}, nil, "tail-burn-ab12")
+	if host != "tail-burn-ab12" || useTLS {
+		t.Fatalf("without MagicDNS: got %q tls=%v", host, useTLS)
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://tail-burn-ab12/secret?x=1", nil)
+	httpsRedirect("tail-burn-ab12.tailnet.ts.net").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected 308, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "https://tail-burn-ab12.tailnet.ts.net/secret?x=1" {
+		t.Fatalf("unexpected Location %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package burn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- INBOX ---
+// Every offer also answers GET /.tail-burn/offers with its metadata and link,
+// but only to identities its target check allows; everyone else gets an
+// empty list. tail-burn inbox asks every online tail-burn-* peer and lists
+// what is waiting, so the link itself never has to leave the tailnet.
+
+const offersPath = "/.tail-burn/offers"
+
+// OfferInfo describes an offer to the receivers it is meant for.
+type OfferInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"` // -1 for a stream
+	Class     string    `json:"class"`
+	Sender    string    `json:"sender"`
+	NotBefore time.Time `json:"notBefore,omitzero"` // embargoed until then
+	Expires   time.Time `json:"expires"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	Link      string    `json:"link"` // path and #fragment on the offering node
+}
+
+// registerInboxHandler lists each offer to the peers its target allows.
+func (s *Server) registerInboxHandler(mux *http.ServeMux, offers func() []recipientOffer) {
+	mux.HandleFunc(offersPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		noCache(w)
+		w.Header().Set("Content-Type", "application/json")
+
+		// Don't even confirm that an offer exists to anyone else
+		visible := []OfferInfo{}
+		for _, o := range offers() {
+			if o.target.Allow(who) {
+				visible = append(visible, o.info)
+			}
+		}
+		if len(visible) > 0 {
+			sender := "A Tailscale User"
+			st, err := s.Client.Status(r.Context())
+			if err == nil && st != nil && st.Self != nil {
+				if profile, ok := st.User[st.Self.UserID]; ok {
+					sender = profile.LoginName
+				}
+			}
+			for i := range visible {
+				visible[i].Sender = sender
+			}
+		}
+		json.NewEncoder(w).Encode(visible)
+	})
+}
+
+// InboxEntry is an offer found on a peer.
+type InboxEntry struct {
+	OfferInfo
+	Host  string // the node's MagicDNS name
+	Owner string // who the tailnet says owns the node
+	Base  string // scheme://host the offer was found at
+}
+
+// URL is the full link to receive the offer.
+func (e InboxEntry) URL() string { return e.Base + e.Link }
+
+// Inbox asks the tailnet for offers addressed to us. Only the network
+// options of opts apply.
+func Inbox(ctx context.Context, opts ReceiveOptions) ([]InboxEntry, error) {
+	client, err := transferClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return listOffers(ctx, opts.localClient(), client)
+}
+
+// listOffers asks every online tail-burn-* peer for offers addressed to us.
+func listOffers(ctx context.Context, lc LocalClient, client *http.Client) ([]InboxEntry, error) {
+	st, err := lc.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tailnet peers: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var entries []InboxEntry
+	for _, peer := range st.Peer {
+		if !peer.Online || !strings.HasPrefix(peer.HostName, "tail-burn-") {
+			continue
+		}
+		host := strings.TrimSuffix(peer.DNSName, ".")
+		owner := peer.HostName
+		if profile, ok := st.User[peer.UserID]; ok {
+			owner = profile.LoginName
+		}
+		wg.Go(func() {
+			offers, base, err := fetchOffers(ctx, client, host)
+			if err != nil {
+				return // not an offer (a request, a receiver) or gone
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, o := range offers {
+				if !strings.HasPrefix(o.Link, "/") {
+					continue // an offer only ever points at its own node
+				}
+				entries = append(entries, InboxEntry{OfferInfo: o, Host: host, Owner: owner, Base: base})
+			}
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Expires.Before(entries[j].Expires) })
+	return entries, nil
+}
+
+// fetchOffers queries one node. Plain HTTP: HTTPS senders redirect.
+func fetchOffers(ctx context.Context, client *http.Client, host string) ([]OfferInfo, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://"+host+offersPath, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var offers []OfferInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&offers); err != nil {
+		return nil, "", err
+	}
+	u := resp.Request.URL
+	return offers, u.Scheme + "://" + u.Host, nil
+}
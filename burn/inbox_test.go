@@ -0,0 +1,96 @@
+package burn
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+)
+
+// serveInbox lists offers on mux the way a Server hosting them does.
+func serveInbox(mux *http.ServeMux, client LocalClient, offers []recipientOffer) {
+	(&Server{Client: client}).registerInboxHandler(mux, func() []recipientOffer { return offers })
+}
+
+func TestInboxHandlerOnlyServesTargets(t *testing.T) {
+	offer := OfferInfo{Name: "plans.pdf", Size: 16, Class: ClassFile, Link: "/secret#sha256=00"}
+	for login, want := range map[string]int{"target@example.com": 1, "mallory@example.com": 0} {
+		f := newTestShare(t, withInbox(recipientOffer{target: loginTarget("target@example.com"), info: offer}))
+		f.client.SetLogin(login)
+		resp, err := http.Get(f.server.URL + offersPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var offers []OfferInfo
+		json.NewDecoder(resp.Body).Decode(&offers)
+		resp.Body.Close()
+		if resp.StatusCode != 200 || len(offers) != want {
+			t.Fatalf("%s: got HTTP %d with %d offer(s), want %d", login, resp.StatusCode, len(offers), want)
+		}
+		if want == 1 && (offers[0].Sender != "sender@example.com" || offers[0].Link != offer.Link) {
+			t.Fatalf("unexpected offer %+v", offers[0])
+		}
+	}
+}
+
+func TestListOffersAndAccept(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	digest, _ := fileDigest(filePath)
+
+	mux := http.NewServeMux()
+	client := burntest.NewClient("target@example.com", "sender@example.com")
+	serveShare(mux, client,
+		&payload{paths: []string{filePath}, name: "plans.pdf", size: 16, linkDigest: digest, browserDigest: digest},
+		&recipient{target: loginTarget("target@example.com"), secretPath: "/secret", done: make(chan string, 1)})
+	serveInbox(mux, client, []recipientOffer{{target: loginTarget("target@example.com"), info: OfferInfo{
+		Name: "plans.pdf", Size: 16, Class: ClassFile, Expires: time.Now().Add(time.Minute),
+		Link: buildLink("/secret", linkSecrets{digest: digest}),
+	}}})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	lc := codePeers(
+		&ipnstate.PeerStatus{HostName: "tail-burn-ab12", DNSName: "tail-burn-ab12.tailnet.ts.net.", Online: true, UserID: 7},
+		&ipnstate.PeerStatus{HostName: "laptop", DNSName: "laptop.tailnet.ts.net.", Online: true},
+	)
+	lc.Users = map[tailcfg.UserID]string{7: "alice@example.com"}
+	var dialed []string
+	opts := ReceiveOptions{
+		Dir:   t.TempDir(),
+		Stall: DefaultStallPolicy,
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}
+	httpClient, _ := transferClient(opts)
+
+	entries, err := listOffers(context.Background(), lc, httpClient)
+	if err != nil {
+		t.Fatalf("listOffers: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Owner != "alice@example.com" || entries[0].Host != "tail-burn-ab12.tailnet.ts.net" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if len(dialed) != 1 || !strings.HasPrefix(dialed[0], "tail-burn-ab12.") {
+		t.Fatalf("expected only the tail-burn peer to be asked, dialed %v", dialed)
+	}
+
+	if _, err := Receive(context.Background(), entries[0].URL(), opts); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(opts.Dir, "plans.pdf")); string(got) != "top secret plans" {
+		t.Fatalf("expected the accepted file, got %q", got)
+	}
+}
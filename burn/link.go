@@ -0,0 +1,66 @@
+package burn
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// --- LINK SECRETS ---
+// Everything the receiver must know but the server must not see rides in
+// the link's #fragment, which browsers and HTTP clients never transmit:
+//
+//	http://tail-burn-1a2b/<secret>#sha256=<hex>&key=<base64url>
+
+// linkSecrets are the fragment parameters of a tail-burn link.
+type linkSecrets struct {
+	digest string // hex SHA-256 of the plaintext payload ("" if absent)
+	key    []byte // offer encryption key (nil if unencrypted)
+}
+
+// buildLink appends the secrets to a link.
+func buildLink(base string, secrets linkSecrets) string {
+	var params []string
+	if secrets.digest != "" {
+		params = append(params, "sha256="+secrets.digest)
+	}
+	if secrets.key != nil {
+		params = append(params, "key="+base64.RawURLEncoding.EncodeToString(secrets.key))
+	}
+	if len(params) == 0 {
+		return base
+	}
+	return base + "#" + strings.Join(params, "&")
+}
+
+// splitLink separates a tail-burn link into the URL that is actually
+// requested and the secrets from its fragment.
+func splitLink(link string) (base string, secrets linkSecrets, err error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", linkSecrets{}, fmt.Errorf("bad request URL: %w", err)
+	}
+	params, err := url.ParseQuery(u.Fragment)
+	if err != nil {
+		return "", linkSecrets{}, fmt.Errorf("bad link fragment: %w", err)
+	}
+	if d := params.Get("sha256"); d != "" {
+		if _, err := hex.DecodeString(d); err != nil || len(d) != sha256.Size*2 {
+			return "", linkSecrets{}, fmt.Errorf("bad digest in link: %q", d)
+		}
+		secrets.digest = strings.ToLower(d)
+	}
+	if k := params.Get("key"); k != "" {
+		key, err := base64.RawURLEncoding.DecodeString(k)
+		if err != nil || len(key) != encKeySize {
+			return "", linkSecrets{}, fmt.Errorf("bad key in link")
+		}
+		secrets.key = key
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), secrets, nil
+}
@@ -0,0 +1,47 @@
+package burn
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitLink(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	base, got, err := splitLink("http://tail-burn-1234/secret#sha256=" + digest)
+	if err != nil {
+		t.Fatalf("splitLink: %v", err)
+	}
+	if base != "http://tail-burn-1234/secret" || got.digest != digest || got.key != nil {
+		t.Fatalf("unexpected split: %q %+v", base, got)
+	}
+
+	base, got, err = splitLink("http://tail-burn-1234/secret")
+	if err != nil || base != "http://tail-burn-1234/secret" || got.digest != "" {
+		t.Fatalf("expected plain link to pass through, got %q %+v %v", base, got, err)
+	}
+
+	if _, _, err := splitLink("http://tail-burn-1234/secret#sha256=nothex"); err == nil {
+		t.Fatalf("expected malformed digest to be rejected")
+	}
+	if _, _, err := splitLink("http://tail-burn-1234/secret#key=c2hvcnQ"); err == nil {
+		t.Fatalf("expected short key to be rejected")
+	}
+}
+
+func TestBuildLinkRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0xfe}, encKeySize)
+	want := linkSecrets{digest: strings.Repeat("cd", 32), key: key}
+	link := buildLink("http://tail-burn-1234/secret", want)
+
+	base, got, err := splitLink(link)
+	if err != nil {
+		t.Fatalf("splitLink(%q): %v", link, err)
+	}
+	if base != "http://tail-burn-1234/secret" || got.digest != want.digest || !bytes.Equal(got.key, key) {
+		t.Fatalf("round trip mismatch: %q %+v", base, got)
+	}
+	if buildLink("http://x/s", linkSecrets{}) != "http://x/s" {
+		t.Fatalf("expected no fragment without secrets")
+	}
+}
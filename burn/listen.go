@@ -0,0 +1,79 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// --- LISTENER (DROP BOX) ---
+// tail-burn listen runs a long-lived node that allowlisted senders push to
+// with send -to-node. A push only carries the offer's link: the listener
+// checks the pusher against -allow, then downloads through the normal
+// receive flow (digest, decryption, ACK), pinned to the node that pushed.
+// Each verified drop can run a -hook.
+
+const pushPath = "/.tail-burn/push"
+
+// pushRequest is what send -to-node posts to a listener.
+type pushRequest struct {
+	Link string `json:"link"` // full link, #fragment included
+}
+
+// registerPushHandler accepts pushes from peers allow lets through and hands
+// them to accept in the background.
+func (s *Server) registerPushHandler(mux *http.ServeMux, allow Authorizer, accept func(from Peer, link string)) {
+	mux.HandleFunc(pushPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		if !allow.Allow(who) || who.Node == nil {
+			s.logf("⛔️ REJECTED push from %s", peerName(who))
+			http.Error(w, "Forbidden", 403)
+			return
+		}
+		var req pushRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if u, err := url.Parse(req.Link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || IsCode(req.Link) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		s.logf("📨 Drop pushed by %s", peerName(who))
+		w.WriteHeader(http.StatusAccepted)
+		go accept(peerOf(who), req.Link)
+	})
+}
+
+// Push tells the tail-burn listener on node about link, which it then
+// downloads like any receiver would.
+func Push(ctx context.Context, client *http.Client, node, link string) error {
+	body, _ := json.Marshal(pushRequest{Link: link})
+	// Plain HTTP: a listener with HTTPS redirects, and the redirect keeps the POST
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://"+node+pushPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach listener %s: %w", node, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("listener %s refused the drop: HTTP %d", node, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package burn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+func TestPushToListener(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "build.tgz")
+	os.WriteFile(filePath, []byte("build artifacts"), 0600)
+	offer := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "build.tgz"}))
+	digest, _ := fileDigest(filePath)
+
+	dest := t.TempDir()
+	listener := newTestShare(t, withPush("alice@", dest))
+	listener.client.SetPeer(burnNode("tail-burn-ab12", "alice@example.com"))
+
+	node := strings.TrimPrefix(listener.server.URL, "http://")
+	if err := Push(context.Background(), http.DefaultClient, node, buildLink(offer.server.URL+"/secret", linkSecrets{digest: digest})); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	select {
+	case err := <-listener.drops:
+		if err != nil {
+			t.Fatalf("drop failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("drop never finished")
+	}
+	want := filepath.Join(dest, "build.tgz")
+	if got, _ := os.ReadFile(want); string(got) != "build artifacts" {
+		t.Fatalf("expected the drop in %s, got %q", dest, got)
+	}
+	if len(listener.saved) != 1 || listener.saved[0] != want {
+		t.Fatalf("expected saved callback with %s, got %v", want, listener.saved)
+	}
+	if offer.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", offer.acks.Load())
+	}
+}
+
+func TestListenerRejectsUnlistedPusher(t *testing.T) {
+	listener := newTestShare(t, withPush("alice@,tag:build", t.TempDir()))
+	listener.client.SetPeer(burnNode("tail-burn-ab12", "mallory@example.com"))
+	err := Push(context.Background(), http.DefaultClient, strings.TrimPrefix(listener.server.URL, "http://"), "http://tail-burn-ab12/secret")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected the push to be refused, got %v", err)
+	}
+	select {
+	case err := <-listener.drops:
+		t.Fatalf("expected no download, got %v", err)
+	default:
+	}
+}
+
+func TestListenerRejectsBadLinks(t *testing.T) {
+	listener := newTestShare(t, withPush("alice@", t.TempDir()))
+	listener.client.SetPeer(burnNode("tail-burn-ab12", "alice@example.com"))
+	for _, link := range []string{"file:///etc/passwd", "7-crossword-lantern", "::"} {
+		err := Push(context.Background(), http.DefaultClient, strings.TrimPrefix(listener.server.URL, "http://"), link)
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("%q: expected 400, got %v", link, err)
+		}
+	}
+}
+
+func TestListenerPinsDownloadToPusher(t *testing.T) {
+	var hits int
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer other.Close()
+
+	// The pusher is nBURN, but the link's host answers as another node
+	dest := t.TempDir()
+	_, err := Receive(context.Background(), other.URL+"/secret", ReceiveOptions{
+		Dir:    dest,
+		Stall:  DefaultStallPolicy,
+		From:   SenderCheck{Node: "nPUSHER"},
+		Client: burntest.NewPeerClient(burnNode("tail-burn-ab12", "alice@example.com")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "not the node that pushed") {
+		t.Fatalf("expected the download to be refused, got %v", err)
+	}
+	if hits != 0 {
+		t.Fatalf("expected no request to another node, got %d", hits)
+	}
+}
@@ -0,0 +1,85 @@
+package burn
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tailscale.com/hostinfo"
+	"tailscale.com/tsnet"
+)
+
+// --- EPHEMERAL NODES ---
+// Both sides can run their own throwaway tsnet node: the sender always does,
+// and receive -authkey does on machines without a running tailscaled. The
+// listen daemon is the exception: it keeps its node (and name) across restarts.
+
+// ephemeralApp is the Hostinfo app an ephemeral node registers with. The
+// netmap doesn't say whether a peer is ephemeral, so this is what a receiver
+// checks instead.
+const ephemeralApp = "tail-burn-ephemeral"
+
+// NewEphemeralNode configures (but doesn't start) an ephemeral node named
+// <prefix>-<random>. The returned cleanup logs it out of the tailnet, shuts
+// it down and shreds its state; it is safe to call more than once.
+func NewEphemeralNode(prefix, authKey string, debug bool) (*tsnet.Server, func(), error) {
+	randSuffix := make([]byte, 2)
+	if _, err := rand.Read(randSuffix); err != nil {
+		return nil, nil, fmt.Errorf("generating hostname suffix: %w", err)
+	}
+	hostname := fmt.Sprintf("%s-%x", prefix, randSuffix)
+
+	configDir, _ := os.UserConfigDir()
+	stateDir := filepath.Join(configDir, "tsnet-"+hostname)
+
+	if err := markStateDir(stateDir); err != nil {
+		return nil, nil, fmt.Errorf("creating state dir: %w", err)
+	}
+
+	hostinfo.SetApp(ephemeralApp)
+	s := NewNode(hostname, stateDir, authKey, debug)
+	s.Ephemeral = true
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() {
+			// Close panics on a node that never started
+			if s.Sys() != nil {
+				// Logging out removes the node now instead of when the
+				// control server notices it is gone
+				if lc, err := s.LocalClient(); err == nil {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					lc.Logout(ctx)
+					cancel()
+				}
+				s.Close()
+			}
+			if err := ShredDir(stateDir); err != nil {
+				log.Printf("⚠️  Could not remove node state %s: %v (run tail-burn gc)", stateDir, err)
+			}
+		})
+	}
+	return s, cleanup, nil
+}
+
+// NewNode configures a node that keeps its state in dir.
+func NewNode(hostname, dir, authKey string, debug bool) *tsnet.Server {
+	// --- LOGGING LOGIC ---
+	var tsLogf func(string, ...any)
+	if debug {
+		tsLogf = log.Printf
+	} else {
+		tsLogf = func(string, ...any) {} // Silent
+	}
+
+	return &tsnet.Server{
+		Hostname: hostname,
+		Dir:      dir,
+		AuthKey:  authKey,
+		Logf:     tsLogf,
+	}
+}
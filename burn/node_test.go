@@ -0,0 +1,25 @@
+package burn
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewEphemeralNode(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("HOME", t.TempDir())
+
+	s, _, err := NewEphemeralNode("tail-burn-recv", "tskey-test", false)
+	if err != nil {
+		t.Fatalf("NewEphemeralNode: %v", err)
+	}
+	if !strings.HasPrefix(s.Hostname, "tail-burn-recv-") || !s.Ephemeral || s.AuthKey != "tskey-test" {
+		t.Fatalf("unexpected node config: %q ephemeral=%v", s.Hostname, s.Ephemeral)
+	}
+	// State must live in its own directory, since cleanup removes it wholesale
+	if filepath.Base(s.Dir) != "tsnet-"+s.Hostname {
+		t.Fatalf("unexpected state dir %q", s.Dir)
+	}
+}
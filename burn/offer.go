@@ -0,0 +1,162 @@
+package burn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// --- OFFERS ---
+// An Offer is one drop: what is sent (files, a stream or a secret), who may
+// collect it, when, and how. Server.Add hosts it; Prepare does the slow part
+// (hashing the payload) up front for callers that want to show the digest
+// before the offer is up.
+
+// Offer describes a one-time drop.
+type Offer struct {
+	// What is offered: exactly one of Paths, Stream and Text.
+	Paths  []string  // files and directories; anything but one file goes out as an archive
+	Stream io.Reader // read once, by the first download
+	Text   []byte    // a one-time secret, zeroed once the offer burns
+	Name   string    // what a Stream is saved as (default "stdin")
+
+	// Who may collect it. Target shares one link between everyone it
+	// allows; Recipients gives each its own link and burn state, and the
+	// offer burns once all of them have collected. With ACL, every peer also
+	// needs a tail-burn grant in the tailnet policy that fits the offer.
+	Target     Authorizer
+	Recipients []Authorizer
+	ACL        bool
+
+	NotBefore time.Time // downloads open then (zero: right away)
+	Expires   time.Time // the offer burns then, collected or not (required)
+
+	Encrypt bool // end-to-end encrypt with a key that only exists in the link
+
+	// Code (from NewCode) also hands the link out for a short code, for up
+	// to CodeAttempts exchanges (0: DefaultCodeAttempts). The node's hostname
+	// must start with the code's nameplate: tail-burn-<nameplate>-.
+	Code         string
+	CodeAttempts int
+
+	p *payload // set by Prepare
+}
+
+// payload is a prepared offer: its source, metadata and digests.
+type payload struct {
+	paths         []string
+	stream        io.Reader
+	secret        []byte
+	name          string
+	size          int64 // -1 for a stream
+	class         string
+	linkDigest    string // SHA-256 the smart client verifies (tar, for archives)
+	browserDigest string // SHA-256 of what a browser downloads (zip, for archives)
+	key           []byte // end-to-end encryption key, nil for plaintext offers
+	salt          []byte // random per-offer salt mixed into every file's stream salt
+}
+
+// displaySize is the size shown on the landing page.
+func (p *payload) displaySize() string {
+	if p.size < 0 {
+		return "unknown size (streamed)"
+	}
+	return FormatBytes(p.size)
+}
+
+// Prepare checks the offer and hashes its payload. Server.Add calls it if
+// it hasn't been called yet.
+func (o *Offer) Prepare() error {
+	if o.p != nil {
+		return nil
+	}
+	sources := 0
+	for _, set := range []bool{len(o.Paths) > 0, o.Stream != nil, o.Text != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("an offer needs exactly one of Paths, Stream and Text")
+	}
+
+	p := &payload{paths: o.Paths, stream: o.Stream}
+	switch {
+	case o.Text != nil:
+		if len(o.Text) == 0 || len(o.Text) > MaxSecretSize {
+			return fmt.Errorf("a secret must be 1 B to %s", FormatBytes(MaxSecretSize))
+		}
+		if o.Encrypt {
+			return fmt.Errorf("a secret can't be encrypted; it never leaves memory")
+		}
+		p.secret = o.Text
+		p.size, p.name, p.class = int64(len(o.Text)), "secret text", ClassText
+		sum := sha256.Sum256(o.Text)
+		p.linkDigest = hex.EncodeToString(sum[:])
+		p.browserDigest = p.linkDigest
+	case o.Stream != nil:
+		if len(o.Recipients) > 0 {
+			return fmt.Errorf("a stream can only be read once; it can't have several recipients")
+		}
+		// Size and digest are only known once the stream ends; they go out as trailers
+		name := o.Name
+		if name == "" {
+			name = "stdin"
+		}
+		p.size, p.name, p.class = -1, filepath.Base(name), ClassFile
+	default:
+		if err := checkPayload(o.Paths); err != nil {
+			return err
+		}
+		size, err := payloadSize(o.Paths)
+		if err != nil {
+			return err
+		}
+		p.size, p.name, p.class = size, payloadName(o.Paths), offerClass(o.Paths)
+		if isArchivePayload(o.Paths) {
+			p.linkDigest, err = archiveDigest(o.Paths, writeTar)
+			if err == nil {
+				p.browserDigest, err = archiveDigest(o.Paths, writeZip)
+			}
+		} else {
+			p.linkDigest, err = fileDigest(o.Paths[0])
+			p.browserDigest = p.linkDigest
+		}
+		if err != nil {
+			return fmt.Errorf("hashing payload: %w", err)
+		}
+	}
+	if o.Encrypt {
+		key, err := newOfferKey()
+		if err != nil {
+			return fmt.Errorf("generating encryption key: %w", err)
+		}
+		p.key = key
+		if p.salt, err = randomSalt(); err != nil {
+			return fmt.Errorf("generating encryption salt: %w", err)
+		}
+	}
+	o.p = p
+	return nil
+}
+
+// Info describes a prepared offer the way the inbox does (without a link).
+func (o *Offer) Info() OfferInfo {
+	info := OfferInfo{NotBefore: o.NotBefore, Expires: o.Expires, Encrypted: o.Encrypt}
+	if o.p != nil {
+		info.Name, info.Size, info.Class = o.p.name, o.p.size, o.p.class
+	}
+	return info
+}
+
+// Digest is the SHA-256 a browser download of the prepared offer has ("" for
+// a stream, whose digest follows it). For archives that is the zip.
+func (o *Offer) Digest() string {
+	if o.p == nil {
+		return ""
+	}
+	return o.p.browserDigest
+}
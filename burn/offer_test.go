@@ -0,0 +1,65 @@
+package burn
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOfferPrepareChecksSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(path, []byte("top secret plans"), 0600)
+	for name, o := range map[string]*Offer{
+		"nothing":          {},
+		"paths and text":   {Paths: []string{path}, Text: []byte("x")},
+		"stream and paths": {Paths: []string{path}, Stream: strings.NewReader("x")},
+		"empty secret":     {Text: []byte{}},
+		"encrypted secret": {Text: []byte("hunter2"), Encrypt: true},
+		"shared stream":    {Stream: strings.NewReader("x"), Recipients: []Authorizer{loginTarget("a"), loginTarget("b")}},
+		"missing path":     {Paths: []string{filepath.Join(t.TempDir(), "gone")}},
+		"oversized secret": {Text: bytes.Repeat([]byte("x"), MaxSecretSize+1)},
+	} {
+		if err := o.Prepare(); err == nil {
+			t.Errorf("%s: expected Prepare to fail", name)
+		}
+	}
+}
+
+func TestOfferInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(path, []byte("top secret plans"), 0600)
+	file := &Offer{Paths: []string{path}, Encrypt: true}
+	if file.Digest() != "" {
+		t.Fatalf("expected no digest before Prepare")
+	}
+	if err := file.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	want, _ := fileDigest(path)
+	if info := file.Info(); info.Name != "plans.pdf" || info.Size != 16 || info.Class != ClassFile || !info.Encrypted {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if file.Digest() != want {
+		t.Fatalf("expected the file's digest %s, got %s", want, file.Digest())
+	}
+
+	stream := &Offer{Stream: strings.NewReader("log line\n")}
+	if err := stream.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if info := stream.Info(); info.Name != "stdin" || info.Size != -1 || stream.Digest() != "" {
+		t.Fatalf("expected an unnamed stream of unknown size, got %+v (digest %q)", info, stream.Digest())
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0600)
+	archive := &Offer{Paths: []string{dir}}
+	if err := archive.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if info := archive.Info(); info.Class != ClassArchive || archive.Digest() == "" || archive.Digest() == archive.p.linkDigest {
+		t.Fatalf("expected an archive whose browser digest is the zip's, got %+v", info)
+	}
+}
@@ -0,0 +1,117 @@
+package burn
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// --- PAKE (SPAKE2) ---
+// A short code has far too little entropy to be hashed into a key: anyone
+// who saw one exchange could try every code offline. SPAKE2 (RFC 9382) over
+// edwards25519 turns it into a strong shared key instead, and each exchange
+// lets an attacker test exactly one guess, online, against a server that
+// counts them:
+//
+//	client: pA = x·G + w·M      server: pB = y·G + w·N
+//	K = h·x·(pB − w·N) = h·y·(pA − w·M)
+//	key = HKDF-SHA256(transcript(pA, pB, K, w), "tail-burn/pake/v1")
+//
+// w is the code hashed to a scalar. M and N are derived from fixed seeds the
+// way the RFC derives its constants, so nobody knows their discrete logs.
+
+const pakeInfo = "tail-burn/pake/v1"
+
+var errPakeMessage = errors.New("invalid PAKE message")
+
+var (
+	pakeM = pakeConstant("tail-burn/spake2/M")
+	pakeN = pakeConstant("tail-burn/spake2/N")
+)
+
+// pakeConstant hashes seed to a point of the prime-order subgroup.
+func pakeConstant(seed string) *edwards25519.Point {
+	h := sha256.Sum256([]byte(seed))
+	for {
+		if p, err := new(edwards25519.Point).SetBytes(h[:]); err == nil {
+			p.MultByCofactor(p)
+			if p.Equal(edwards25519.NewIdentityPoint()) == 0 {
+				return p
+			}
+		}
+		h = sha256.Sum256(h[:])
+	}
+}
+
+// pakeScalar hashes a code to w.
+func pakeScalar(code string) *edwards25519.Scalar {
+	h := sha512.Sum512([]byte(pakeInfo + "\x00" + code))
+	w, _ := edwards25519.NewScalar().SetUniformBytes(h[:])
+	return w
+}
+
+// pakeState is one side of a SPAKE2 exchange.
+type pakeState struct {
+	client bool
+	w, x   *edwards25519.Scalar
+	msg    []byte // our public message (pA or pB)
+}
+
+// newPake starts an exchange for code; msg is sent to the other side.
+func newPake(code string, client bool) (*pakeState, error) {
+	seed := make([]byte, 64)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	x, _ := edwards25519.NewScalar().SetUniformBytes(seed)
+	w := pakeScalar(code)
+	blind := pakeN
+	if client {
+		blind = pakeM
+	}
+	// msg = x·G + w·blind
+	p := new(edwards25519.Point).ScalarBaseMult(x)
+	p.Add(p, new(edwards25519.Point).ScalarMult(w, blind))
+	return &pakeState{client: client, w: w, x: x, msg: p.Bytes()}, nil
+}
+
+// finish combines the peer's message into the shared key. A wrong code on
+// either side yields a different key, not an error.
+func (s *pakeState) finish(peerMsg []byte) ([]byte, error) {
+	peer, err := new(edwards25519.Point).SetBytes(peerMsg)
+	if err != nil {
+		return nil, errPakeMessage
+	}
+	blind := pakeM
+	if s.client {
+		blind = pakeN
+	}
+	// K = h·x·(peer − w·blind); a small-order peer message ends up as identity
+	k := new(edwards25519.Point).Subtract(peer, new(edwards25519.Point).ScalarMult(s.w, blind))
+	k.MultByCofactor(k)
+	k.ScalarMult(s.x, k)
+	if k.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, errPakeMessage
+	}
+
+	pA, pB := s.msg, peerMsg
+	if !s.client {
+		pA, pB = peerMsg, s.msg
+	}
+	var transcript []byte
+	for _, part := range [][]byte{pA, pB, k.Bytes(), s.w.Bytes()} {
+		transcript = binary.LittleEndian.AppendUint64(transcript, uint64(len(part)))
+		transcript = append(transcript, part...)
+	}
+	key, err := hkdf.Key(sha256.New, transcript, nil, pakeInfo, encKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving PAKE key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,68 @@
+package burn
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/edwards25519"
+)
+
+func pakePair(t *testing.T, clientCode, serverCode string) (clientKey, serverKey []byte) {
+	t.Helper()
+	client, err := newPake(clientCode, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := newPake(serverCode, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientKey, err = client.finish(server.msg); err != nil {
+		t.Fatalf("client finish: %v", err)
+	}
+	if serverKey, err = server.finish(client.msg); err != nil {
+		t.Fatalf("server finish: %v", err)
+	}
+	return clientKey, serverKey
+}
+
+func TestPakeAgreesOnKey(t *testing.T) {
+	a, b := pakePair(t, "7-crossword-lantern", "7-crossword-lantern")
+	if !bytes.Equal(a, b) || len(a) != encKeySize {
+		t.Fatalf("expected matching %d-byte keys, got %x / %x", encKeySize, a, b)
+	}
+	c, _ := pakePair(t, "7-crossword-lantern", "7-crossword-lantern")
+	if bytes.Equal(a, c) {
+		t.Fatalf("expected a fresh key per exchange")
+	}
+}
+
+func TestPakeWrongCodeDisagrees(t *testing.T) {
+	a, b := pakePair(t, "7-crossword-lantern", "7-crossword-lanterns")
+	if bytes.Equal(a, b) {
+		t.Fatalf("expected different keys for different codes")
+	}
+}
+
+func TestPakeRejectsBadMessages(t *testing.T) {
+	s, _ := newPake("7-crossword-lantern", false)
+	if _, err := s.finish([]byte("short")); err == nil {
+		t.Fatalf("expected a malformed message to be rejected")
+	}
+	// w·M makes the unblinded point the identity
+	p := new(edwards25519.Point).ScalarMult(pakeScalar("7-crossword-lantern"), pakeM)
+	if _, err := s.finish(p.Bytes()); err == nil {
+		t.Fatalf("expected an identity key to be rejected")
+	}
+}
+
+func TestPakeConstants(t *testing.T) {
+	if pakeM.Equal(pakeN) == 1 {
+		t.Fatalf("M and N must differ")
+	}
+	for _, p := range []*edwards25519.Point{pakeM, pakeN} {
+		if p.Equal(edwards25519.NewIdentityPoint()) == 1 || p.Equal(edwards25519.NewGeneratorPoint()) == 1 {
+			t.Fatalf("bad constant %x", p.Bytes())
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package burn
+
+import "os"
+
+// processAlive reports whether a process with this PID exists. FindProcess
+// opens a handle, which fails once the process is gone.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
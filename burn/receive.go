@@ -0,0 +1,513 @@
+package burn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tailscale.com/client/local"
+)
+
+// --- RECEIVE ---
+
+// ReceiveOptions configures Receive.
+type ReceiveOptions struct {
+	Dir    string    // where files and archives land (default ".")
+	Output string    // exact path for a single file, instead of its name under Dir
+	Writer io.Writer // write the payload (or secret) here instead; nothing touches the disk
+	Resume bool      // continue a .part left by an earlier attempt
+
+	Stall StallPolicy // when a transfer that stopped moving is given up
+	From  SenderCheck // only accept the offer from this sender's node
+
+	Client LocalClient // resolves peers for From (default: the local tailscaled)
+	Dial   DialFunc    // dials the sender (default: the host network)
+
+	Status io.Writer // human-readable progress (default: discarded)
+
+	// OnStart is called once the server answered with the offer, before any
+	// of it is read. An error aborts the transfer without burning it.
+	OnStart func(Transfer) error
+	// OnProgress is called about once a second with the bytes received so
+	// far on the wire and the total (-1: unknown), and once more at the end.
+	OnProgress func(received, total int64)
+}
+
+func (o ReceiveOptions) status() io.Writer {
+	if o.Status == nil {
+		return io.Discard
+	}
+	return o.Status
+}
+
+// localClient is opts.Client, or the system tailscaled.
+func (o ReceiveOptions) localClient() LocalClient {
+	if o.Client == nil {
+		return &local.Client{}
+	}
+	return o.Client
+}
+
+// Transfer is the offer a server answered with.
+type Transfer struct {
+	URL   string
+	Name  string
+	Size  int64 // on the wire; -1 for a stream
+	Class string
+}
+
+// Result is what Receive got.
+type Result struct {
+	Name   string
+	Paths  []string // what was saved; none for a Writer or a secret
+	Digest string   // the verified SHA-256 ("" if the link had none)
+	Secret []byte   // a one-time secret, unless it went to the Writer; clear it when done
+	Burned bool     // the server confirmed the offer burned
+}
+
+// Receive fetches the offer behind link (or a short code), verifies and
+// saves it, and confirms receipt, which burns the offer. Errors match
+// ErrForbidden and ErrGone where they apply.
+func Receive(ctx context.Context, link string, opts ReceiveOptions) (*Result, error) {
+	status := opts.status()
+	fmt.Fprintln(status, "🔍 Connecting to tail-burn server...")
+
+	// A short code is exchanged for the full link first
+	if IsCode(link) {
+		var err error
+		if link, err = redeemCode(ctx, link, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// The expected digest and the key ride in the #fragment and are never sent
+	url, secrets, err := splitLink(link)
+	if err != nil {
+		return nil, err
+	}
+	wantDigest, key := secrets.digest, secrets.key
+	if opts.Writer != nil && opts.Resume {
+		return nil, fmt.Errorf("-resume needs a file; it can't be combined with -o -")
+	}
+
+	destDir := opts.Dir
+	if destDir == "" {
+		destDir = "."
+	}
+	// savePath is where a single file lands: -o, or its own name under -dir
+	savePath := func(name string) string {
+		if opts.Output != "" {
+			return opts.Output
+		}
+		return filepath.Join(destDir, name)
+	}
+	client, err := transferClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0. Probe for a resumable .part left by an earlier attempt
+	var offset int64
+	var etag string
+	if opts.Resume {
+		head, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bad request URL: %w", err)
+		}
+		head.Header.Set("X-Tail-Burn-Client", "true")
+		headResp, err := client.Do(head)
+		if err != nil {
+			return nil, fmt.Errorf("connection failed: %w", err)
+		}
+		headResp.Body.Close()
+		if err := notYet(headResp); err != nil {
+			return nil, err
+		}
+		if headResp.StatusCode != 200 {
+			return nil, &statusError{what: "request", status: headResp.StatusCode}
+		}
+		if headResp.Header.Get("X-Tail-Burn-Archive") == "" {
+			etag = headResp.Header.Get("ETag")
+			partPath := savePath(attachmentName(headResp.Header)) + ".part"
+			offset = resumeOffset(partPath, etag)
+			if key != nil {
+				// Ciphertext can only be resumed on a chunk boundary
+				offset -= offset % encChunkSize
+			}
+			if offset > 0 {
+				fmt.Fprintf(status, "⏩ Resuming '%s' at %s...\n", partPath, FormatBytes(offset))
+			}
+		}
+	}
+
+	// 1. Start Download Request
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bad request URL: %w", err)
+	}
+	req.Header.Set("X-Tail-Burn-Client", "true") // Identify ourselves
+	if offset > 0 {
+		wireOffset := offset
+		if key != nil {
+			wireOffset = offset / encChunkSize * (encChunkSize + encTagSize)
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", wireOffset))
+		req.Header.Set("If-Range", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := notYet(resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 && !(offset > 0 && resp.StatusCode == http.StatusPartialContent) {
+		return nil, &statusError{what: "request", status: resp.StatusCode}
+	}
+	if sc := scheduleOf(resp); !sc.expires.IsZero() {
+		fmt.Fprintf(status, "⏳ Link burns in %s (or once received).\n", FormatRemaining(time.Until(sc.expires)))
+	}
+
+	// Refuse a downgrade to plaintext, and ciphertext we can't open
+	var body io.Reader = progress(resp.Body, resp.ContentLength, opts.OnProgress)
+	scheme := resp.Header.Get(encHeader)
+	switch {
+	case key != nil && scheme == "":
+		return nil, fmt.Errorf("link is encrypted but the server sent plaintext; refusing")
+	case key == nil && scheme != "":
+		return nil, fmt.Errorf("offer is encrypted but the link has no key; ask for the full link")
+	case key != nil:
+		if scheme != encScheme {
+			return nil, fmt.Errorf("unsupported encryption scheme %q", scheme)
+		}
+		salt, err := hex.DecodeString(resp.Header.Get(encSaltHeader))
+		if err != nil {
+			return nil, fmt.Errorf("bad encryption salt: %w", err)
+		}
+		aead, err := streamAEAD(key, salt)
+		if err != nil {
+			return nil, err
+		}
+		var start uint64
+		if resp.StatusCode == http.StatusPartialContent {
+			start = uint64(offset / encChunkSize)
+		}
+		body = newDecryptReader(body, aead, start)
+		fmt.Fprintln(status, "🔐 Decrypting end-to-end encrypted stream...")
+	}
+	streamed := isStream(resp)
+	if wantDigest == "" && !streamed {
+		fmt.Fprintln(status, "⚠️  Link has no SHA-256; only the size can be checked.")
+	}
+
+	// Extract Filename
+	filename := attachmentName(resp.Header)
+	res := &Result{Name: filename}
+	var receivedDigest string // reported back with the ACK
+	class := ClassFile
+	switch {
+	case resp.Header.Get(textHeader) == "true":
+		class = ClassText
+	case resp.Header.Get("X-Tail-Burn-Archive") == "tar":
+		class = ClassArchive
+	}
+	if opts.OnStart != nil {
+		if err := opts.OnStart(Transfer{URL: url, Name: filename, Size: resp.ContentLength, Class: class}); err != nil {
+			return nil, err
+		}
+	}
+
+	if class == ClassText {
+		// 2c. One-time secret: to the caller (or a pipe), never to disk
+		if opts.Output != "" {
+			return nil, fmt.Errorf("secrets are never written to disk; use -o - to pipe one")
+		}
+		secret, err := io.ReadAll(io.LimitReader(body, MaxSecretSize+1))
+		if opts.Writer != nil {
+			defer clear(secret)
+		}
+		if err != nil {
+			clear(secret)
+			return nil, fmt.Errorf("download interrupted: %w", err)
+		}
+		if len(secret) > MaxSecretSize {
+			clear(secret)
+			return nil, fmt.Errorf("secret is larger than %s; refusing", FormatBytes(MaxSecretSize))
+		}
+		sum := sha256.Sum256(secret)
+		if err := checkDigest(wantDigest, sum[:]); err != nil {
+			clear(secret)
+			return nil, err
+		}
+		if wantDigest != "" {
+			fmt.Fprintln(status, "🔒 SHA-256 verified.")
+		}
+		receivedDigest = hex.EncodeToString(sum[:])
+		if opts.Writer != nil {
+			if _, err := opts.Writer.Write(secret); err != nil {
+				return nil, fmt.Errorf("cannot write secret: %w", err)
+			}
+		} else {
+			res.Secret = secret
+		}
+	} else if opts.Writer != nil {
+		// 2d. Straight to the writer (a file, a stream, or the raw tar); nothing touches the disk
+		fmt.Fprintf(status, "📥 Writing '%s' to stdout...\n", filename)
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(opts.Writer, h), body)
+		if err != nil {
+			return nil, fmt.Errorf("download interrupted: %w", err)
+		}
+		if err := verifyBody(resp, resp.ContentLength, key, wantDigest, n, h.Sum(nil)); err != nil {
+			return nil, err
+		}
+		if wantDigest != "" || streamed {
+			fmt.Fprintln(status, "🔒 SHA-256 verified.")
+		}
+		receivedDigest = hex.EncodeToString(h.Sum(nil))
+		fmt.Fprintf(status, "✅ Download complete (%s)\n", FormatBytes(n))
+	} else if class == ClassArchive {
+		if opts.Output != "" {
+			return nil, fmt.Errorf("an archive can't be saved with -o; use -dir (or -o - for the raw tar)")
+		}
+		// 2a. Unpack Archive
+		fmt.Fprintf(status, "📥 Unpacking '%s' into '%s'...\n", filename, destDir)
+		h := sha256.New()
+		body := io.TeeReader(body, h)
+		created, err := extractTar(body, destDir, status)
+		if err == nil {
+			// Hash the end-of-archive padding too
+			_, err = io.Copy(io.Discard, body)
+		}
+		if err == nil {
+			err = checkDigest(wantDigest, h.Sum(nil))
+			if err != nil {
+				for _, name := range created {
+					os.RemoveAll(filepath.Join(destDir, name))
+				}
+				fmt.Fprintln(status, "🗑️  Removed the unpacked files.")
+				return nil, err
+			}
+		}
+		for _, name := range created {
+			fmt.Fprintf(status, "   → %s\n", filepath.Join(destDir, name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range created {
+			res.Paths = append(res.Paths, filepath.Join(destDir, name))
+		}
+		if wantDigest != "" {
+			fmt.Fprintln(status, "🔒 SHA-256 verified.")
+		}
+		receivedDigest = hex.EncodeToString(h.Sum(nil))
+		fmt.Fprintln(status, "✅ Download complete")
+	} else {
+		target := savePath(filename)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("cannot create directory: %w", err)
+		}
+
+		// Stream into <name>.part so an interrupted download can be resumed
+		partPath := target + ".part"
+		total := resp.ContentLength
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if resp.StatusCode == http.StatusPartialContent {
+			flags = os.O_WRONLY | os.O_APPEND
+			total = contentRangeTotal(resp.Header.Get("Content-Range"))
+			// Drop anything past the resume point (a partial encrypted chunk)
+			if err := os.Truncate(partPath, offset); err != nil {
+				return nil, fmt.Errorf("cannot resume partial file: %w", err)
+			}
+		} else {
+			if offset > 0 {
+				fmt.Fprintln(status, "⚠️  File changed on the server. Starting over.")
+			}
+			offset = 0
+		}
+		h := sha256.New()
+		if offset > 0 {
+			// Account for the bytes an earlier attempt already saved
+			if err := hashFile(h, partPath); err != nil {
+				return nil, fmt.Errorf("cannot read partial file: %w", err)
+			}
+		}
+		out, err := os.OpenFile(partPath, flags, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create file: %w", err)
+		}
+		defer out.Close()
+		// Streams have no ETag: there is nothing to resume against
+		resumable := resp.Header.Get("ETag") != ""
+		if resumable {
+			if err := os.WriteFile(partETagPath(partPath), []byte(resp.Header.Get("ETag")), 0644); err != nil {
+				return nil, fmt.Errorf("cannot create file: %w", err)
+			}
+		}
+
+		// 2b. Stream Data
+		fmt.Fprintf(status, "📥 Downloading '%s'...\n", filename)
+		n, err := io.Copy(io.MultiWriter(out, h), body)
+		size := offset + n
+		if err != nil {
+			if !resumable {
+				out.Close()
+				os.Remove(partPath)
+				fmt.Fprintln(status, "🗑️  Deleted the partial stream; a stream can't be resumed.")
+				return nil, fmt.Errorf("download interrupted: %w", err)
+			}
+			fmt.Fprintf(status, "💾 Kept %s in '%s'. Run again with -resume to continue.\n", FormatBytes(size), partPath)
+			return nil, fmt.Errorf("download interrupted: %w", err)
+		}
+		if err := out.Close(); err != nil {
+			return nil, fmt.Errorf("cannot write file: %w", err)
+		}
+		// FIX: Check Content-Length integrity, the digest, and a stream's trailers
+		if err := verifyBody(resp, total, key, wantDigest, size, h.Sum(nil)); err != nil {
+			// Never keep (or resume) bytes that failed verification
+			os.Remove(partPath)
+			os.Remove(partETagPath(partPath))
+			fmt.Fprintln(status, "🗑️  Deleted the corrupted download.")
+			return nil, err
+		}
+		if wantDigest != "" || streamed {
+			fmt.Fprintln(status, "🔒 SHA-256 verified.")
+		}
+		receivedDigest = hex.EncodeToString(h.Sum(nil))
+
+		// --- AUTO-RENAME LOGIC ---
+		// (-o names the file explicitly and replaces it)
+		if opts.Output == "" {
+			safeName := getSafeFilename(target)
+			if safeName != target {
+				fmt.Fprintf(status, "⚠️  File '%s' exists. Saving as '%s' instead.\n", target, safeName)
+			}
+			target = safeName
+		}
+		// -------------------------
+
+		if err := os.Rename(partPath, target); err != nil {
+			return nil, fmt.Errorf("cannot save file: %w", err)
+		}
+		os.Remove(partETagPath(partPath))
+		res.Paths = []string{target}
+		fmt.Fprintf(status, "✅ Download complete (%s)\n", FormatBytes(size))
+	}
+
+	res.Digest = receivedDigest
+
+	// 3. Send ACK (The Kill Switch)
+	fmt.Fprintln(status, "📡 Sending kill signal to server...")
+	ackURL := url + "/ack"
+	ackReq, err := http.NewRequestWithContext(ctx, "POST", ackURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bad request URL: %w", err)
+	}
+	ackReq.Header.Set("Content-Type", "text/plain")
+	ackReq.Header.Set(tokenHeader, resp.Header.Get(tokenHeader))
+	ackReq.Header.Set(digestHeader, receivedDigest)
+	ackResp, err := client.Do(ackReq)
+	if err == nil {
+		defer ackResp.Body.Close()
+		if ackResp.StatusCode == 200 {
+			fmt.Fprintln(status, "💥 Server confirmed destruction.")
+			res.Burned = true
+		} else {
+			fmt.Fprintln(status, "⚠️ Server responded but did not confirm destruction.")
+		}
+	} else {
+		fmt.Fprintln(status, "⚠️ Server may have already timed out (Link is dead).")
+	}
+	return res, nil
+}
+
+// attachmentName extracts the file name from Content-Disposition. Path
+// components are stripped: the server never gets to choose where we write.
+func attachmentName(h http.Header) string {
+	contentDisp := h.Get("Content-Disposition")
+	filename := "downloaded_file"
+	if strings.Contains(contentDisp, "filename=") {
+		parts := strings.Split(contentDisp, "filename=\"")
+		if len(parts) > 1 {
+			filename = strings.TrimSuffix(parts[1], "\"")
+		}
+	}
+	return filepath.Base(filename)
+}
+
+// --- HELPER: Find a unique filename (test.bin -> test-1.bin) ---
+func getSafeFilename(name string) string {
+	// If the file doesn't exist, use the original name
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return name
+	}
+
+	// Split "test.bin" into "test" and ".bin"
+	var base, ext string
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		base = name[:idx]
+		ext = name[idx:]
+	} else {
+		base = name
+	}
+
+	// Loop until we find a name that doesn't exist
+	for i := 1; ; i++ {
+		newName := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Stat(newName); os.IsNotExist(err) {
+			return newName
+		}
+	}
+}
+
+// FormatBytes formats a size for people (1.5 MB).
+func FormatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// progressReader reports how far a download got.
+type progressReader struct {
+	r        io.Reader
+	n, total int64
+	last     time.Time
+	report   func(received, total int64)
+}
+
+// progress wraps r to report progress towards total (-1: unknown) bytes.
+// Without a report func, r is returned as is.
+func progress(r io.Reader, total int64, report func(received, total int64)) io.Reader {
+	if report == nil {
+		return r
+	}
+	return &progressReader{r: r, total: total, last: time.Now(), report: report}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if err != nil || time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.report(p.n, p.total)
+	}
+	return n, err
+}
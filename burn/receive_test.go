@@ -0,0 +1,90 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+// hostFile hosts a file for target@example.com and returns its offer.
+func hostFile(t *testing.T, content string) *Hosted {
+	t.Helper()
+	srv := newTestServer(t, burntest.NewClient("target@example.com", ""))
+	h, err := srv.Add(&Offer{
+		Paths:   []string{writeOfferFile(t, content)},
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	t.Cleanup(func() { h.Burn("test over") })
+	return h
+}
+
+func TestReceiveToWriter(t *testing.T) {
+	h := hostFile(t, "top secret plans")
+	dir := t.TempDir()
+	var out bytes.Buffer
+	res, err := Receive(context.Background(), h.Links()[0].URL, ReceiveOptions{Dir: dir, Writer: &out, Stall: DefaultStallPolicy})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if out.String() != "top secret plans" || len(res.Paths) != 0 || res.Name != "plans.pdf" {
+		t.Fatalf("expected the payload in the writer only, got %q, %+v", out.String(), res)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected nothing on disk, got %v", entries)
+	}
+	<-h.Done()
+}
+
+func TestReceiveOnStartAborts(t *testing.T) {
+	h := hostFile(t, "top secret plans")
+	refuse := errors.New("not today")
+	var started Transfer
+	_, err := Receive(context.Background(), h.Links()[0].URL, ReceiveOptions{
+		Dir:   t.TempDir(),
+		Stall: DefaultStallPolicy,
+		OnStart: func(tr Transfer) error {
+			started = tr
+			return refuse
+		},
+	})
+	if !errors.Is(err, refuse) {
+		t.Fatalf("expected OnStart's error, got %v", err)
+	}
+	if started.Name != "plans.pdf" || started.Size != 16 || started.Class != ClassFile {
+		t.Fatalf("unexpected transfer %+v", started)
+	}
+	if h.Reason() != "" {
+		t.Fatalf("expected an aborted receive not to burn the offer, got %q", h.Reason())
+	}
+}
+
+func TestReceiveReportsProgress(t *testing.T) {
+	h := hostFile(t, "top secret plans")
+	var last atomic.Int64
+	_, err := Receive(context.Background(), h.Links()[0].URL, ReceiveOptions{
+		Dir:   t.TempDir(),
+		Stall: DefaultStallPolicy,
+		OnProgress: func(received, total int64) {
+			if total != 16 {
+				t.Errorf("expected a total of 16, got %d", total)
+			}
+			last.Store(received)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if last.Load() != 16 {
+		t.Fatalf("expected progress to end at 16 bytes, got %d", last.Load())
+	}
+}
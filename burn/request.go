@@ -0,0 +1,246 @@
+package burn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// --- REVERSE DROP ---
+// `tail-burn request` is the mirror image of send: it hosts a one-time upload
+// endpoint and accepts exactly one file from the named identity, then burns.
+// `tail-burn fulfill` (or the browser upload page) answers it.
+
+const uploadNameHeader = "X-Tail-Burn-Name"
+
+// uploadData fills uploadHTMLTemplate.
+type uploadData struct {
+	Requester string
+}
+
+// registerUploadHandlers serves the upload page and endpoint of a request
+// and saves the one upload to destDir.
+func (s *Server) registerUploadHandlers(mux *http.ServeMux, destDir string, share *recipient) {
+	var used atomic.Bool
+	var inProgress atomic.Bool
+
+	mux.HandleFunc(share.secretPath, func(w http.ResponseWriter, r *http.Request) {
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		if !share.target.Allow(who) {
+			s.blocked(share.hosted, who)
+			http.Error(w, "Forbidden", 403)
+			return
+		}
+
+		isSmartClient := r.Header.Get("X-Tail-Burn-Client") == "true"
+		gone := func() {
+			if !isSmartClient {
+				w.WriteHeader(http.StatusGone)
+				_ = burnedTemplate.Execute(w, nil)
+				return
+			}
+			http.Error(w, "Gone", http.StatusGone)
+		}
+		if used.Load() {
+			gone()
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			requester := "A Tailscale User"
+			st, err := s.Client.Status(r.Context())
+			if err == nil && st != nil && st.Self != nil {
+				if profile, ok := st.User[st.Self.UserID]; ok {
+					requester = profile.LoginName
+				}
+			}
+			if err := uploadTemplate.Execute(w, uploadData{Requester: requester}); err != nil {
+				http.Error(w, "Template Error", http.StatusInternalServerError)
+			}
+			return
+		case "POST":
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Exactly one upload: a failed one frees the slot again
+		if !inProgress.CompareAndSwap(false, true) {
+			gone()
+			return
+		}
+		success := false
+		defer func() {
+			if !success {
+				inProgress.Store(false)
+			}
+		}()
+
+		name, body, err := uploadBody(r, isSmartClient)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.logf("📥 Receiving '%s' from %s...", name, peerName(who))
+
+		saved, digest, err := saveUpload(destDir, name, body, r.Header.Get(digestHeader))
+		if err != nil {
+			s.logf("❌ Upload failed: %v", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, errDigestMismatch) {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		success = true
+		used.Store(true)
+		s.logf("✅ Saved '%s' (SHA-256 %s)", saved, digest)
+
+		if isSmartClient {
+			w.Write([]byte("OK"))
+		} else {
+			_ = uploadedTemplate.Execute(w, nil)
+		}
+		select {
+		case share.done <- ReasonUpload:
+		default:
+		}
+	})
+}
+
+// uploadBody finds the file in an upload: the raw body for fulfill, the
+// "file" field of the form for browsers.
+func uploadBody(r *http.Request, isSmartClient bool) (string, io.Reader, error) {
+	if isSmartClient {
+		return uploadName(r.Header.Get(uploadNameHeader)), r.Body, nil
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", nil, fmt.Errorf("expected a form upload")
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return "", nil, fmt.Errorf("no file in upload")
+		}
+		if part.FormName() == "file" {
+			return uploadName(part.FileName()), part, nil
+		}
+	}
+}
+
+// uploadName keeps only the base name an uploader suggests.
+func uploadName(name string) string {
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	name = filepath.Base(filepath.Clean("/" + strings.ReplaceAll(name, "\\", "/")))
+	if name == "/" || name == "." || name == "" {
+		return "upload.bin"
+	}
+	return name
+}
+
+// saveUpload streams body into destDir under a safe version of name. On a
+// digest mismatch (want, if set) nothing is kept.
+func saveUpload(destDir, name string, body io.Reader, want string) (string, string, error) {
+	tmp, err := os.CreateTemp(destDir, ".tail-burn-upload-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if want != "" {
+		if err := checkDigest(want, h.Sum(nil)); err != nil {
+			return "", "", err
+		}
+	}
+
+	final := getSafeFilename(filepath.Join(destDir, name))
+	if err := os.Rename(tmp.Name(), final); err != nil {
+		return "", "", err
+	}
+	return final, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Fulfill uploads path to a request link. Only the network options of opts
+// apply; progress goes to opts.Status.
+func Fulfill(ctx context.Context, url, path string, opts ReceiveOptions) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	status := opts.status()
+	fmt.Fprintln(status, "🔒 Hashing file...")
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	client, err := transferClient(opts)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, file)
+	if err != nil {
+		return fmt.Errorf("bad request URL: %w", err)
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("X-Tail-Burn-Client", "true")
+	req.Header.Set(uploadNameHeader, mime.QEncoding.Encode("utf-8", filepath.Base(path)))
+	req.Header.Set(digestHeader, digest)
+
+	fmt.Fprintf(status, "📤 Uploading '%s' (%s)...\n", filepath.Base(path), FormatBytes(fi.Size()))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		fmt.Fprintln(status, "✅ Delivered. The request link is now burned.")
+		return nil
+	case http.StatusForbidden:
+		return refusal{fmt.Errorf("access denied: this request isn't addressed to you")}
+	case http.StatusGone:
+		return fmt.Errorf("request link has already been used")
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("requester refused the upload (%s): %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
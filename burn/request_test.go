@@ -0,0 +1,136 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeUploadFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return path
+}
+
+func TestFulfillDeliversOnceAndBurns(t *testing.T) {
+	dest := t.TempDir()
+	f := newTestShare(t, withUploads(dest), withRecipient(&recipient{target: loginTarget("colleague@example.com")}))
+	os.WriteFile(filepath.Join(dest, "logs.tgz"), []byte("already here"), 0644)
+	path := writeUploadFile(t, "logs.tgz", "log bundle")
+
+	opts := ReceiveOptions{Stall: DefaultStallPolicy}
+	if err := Fulfill(context.Background(), f.server.URL+"/secret", path, opts); err != nil {
+		t.Fatalf("fulfill: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "logs-1.tgz"))
+	if err != nil || string(got) != "log bundle" {
+		t.Fatalf("expected upload saved under a safe name, got %q (%v)", got, err)
+	}
+	select {
+	case <-f.shutdown:
+	default:
+		t.Fatalf("expected the request to burn after one upload")
+	}
+
+	err = Fulfill(context.Background(), f.server.URL+"/secret", path, opts)
+	if err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Fatalf("expected second upload to be refused, got %v", err)
+	}
+}
+
+func TestFulfillRejectsOtherIdentity(t *testing.T) {
+	dest := t.TempDir()
+	f := newTestShare(t, withUploads(dest), withRecipient(&recipient{target: loginTarget("colleague@example.com")}))
+	f.client.SetLogin("mallory@example.com")
+	path := writeUploadFile(t, "evil.sh", "rm -rf /")
+
+	err := Fulfill(context.Background(), f.server.URL+"/secret", path, ReceiveOptions{Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("expected 403, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing saved, got %v", entries)
+	}
+}
+
+func TestUploadDigestMismatchKeepsNothing(t *testing.T) {
+	dest := t.TempDir()
+	f := newTestShare(t, withUploads(dest), withRecipient(&recipient{target: loginTarget("colleague@example.com")}))
+	req, _ := http.NewRequest("POST", f.server.URL+"/secret", strings.NewReader("corrupted"))
+	req.Header.Set("X-Tail-Burn-Client", "true")
+	req.Header.Set(uploadNameHeader, "key.pem")
+	req.Header.Set(digestHeader, strings.Repeat("00", 32))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing saved, got %v", entries)
+	}
+
+	// A failed upload doesn't use up the request
+	path := writeUploadFile(t, "key.pem", "the real key")
+	if err := Fulfill(context.Background(), f.server.URL+"/secret", path, ReceiveOptions{Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("retry after failed upload: %v", err)
+	}
+}
+
+func TestBrowserUpload(t *testing.T) {
+	dest := t.TempDir()
+	f := newTestShare(t, withUploads(dest), withRecipient(&recipient{target: loginTarget("colleague@example.com")}))
+
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), `type="file"`) {
+		t.Fatalf("expected an upload form")
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "../../etc/passwd")
+	fw.Write([]byte("not really"))
+	mw.Close()
+	resp, err = http.Post(f.server.URL+"/secret", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got, err := os.ReadFile(filepath.Join(dest, "passwd")); err != nil || string(got) != "not really" {
+		t.Fatalf("expected upload confined to dest as 'passwd', got %q (%v)", got, err)
+	}
+}
+
+func TestUploadName(t *testing.T) {
+	for in, want := range map[string]string{
+		"report.pdf":            "report.pdf",
+		"../../etc/passwd":      "passwd",
+		`C:\Users\me\key.pem`:   "key.pem",
+		"":                      "upload.bin",
+		"..":                    "upload.bin",
+		"=?utf-8?q?caf=C3=A9?=": "café",
+	} {
+		if got := uploadName(in); got != want {
+			t.Errorf("uploadName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
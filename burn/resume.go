@@ -0,0 +1,253 @@
+package burn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- RANGE REQUESTS & BYTE COVERAGE ---
+// A single-file offer can be fetched in pieces (Range / If-Range), so a
+// dropped connection no longer means starting over. The offer only counts as
+// delivered once every byte has been served at least once to the same
+// identity.
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange is the half-open interval [start, end).
+type byteRange struct {
+	start, end int64
+}
+
+// coverage records which bytes of one version (ETag) of a file were served.
+type coverage struct {
+	mu     sync.Mutex
+	etag   string
+	ranges []byteRange // sorted, non-overlapping
+}
+
+// add marks [start, end) of the file version etag as served. Serving a
+// different version discards what was recorded for the old one.
+func (c *coverage) add(etag string, start, end int64) {
+	if end <= start {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if etag != c.etag {
+		c.etag = etag
+		c.ranges = nil
+	}
+
+	c.ranges = append(c.ranges, byteRange{start, end})
+	sort.Slice(c.ranges, func(i, j int) bool { return c.ranges[i].start < c.ranges[j].start })
+	merged := c.ranges[:1]
+	for _, r := range c.ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.end {
+			if r.end > last.end {
+				last.end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	c.ranges = merged
+}
+
+// served returns how many distinct bytes of version etag were served.
+func (c *coverage) served(etag string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if etag != c.etag {
+		return 0
+	}
+	var n int64
+	for _, r := range c.ranges {
+		n += r.end - r.start
+	}
+	return n
+}
+
+// complete reports whether all size bytes of version etag were served.
+func (c *coverage) complete(etag string, size int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size == 0 {
+		return true
+	}
+	return etag == c.etag && len(c.ranges) == 1 && c.ranges[0].start == 0 && c.ranges[0].end >= size
+}
+
+// peerCoverage keeps a separate coverage per identity, so bytes served to
+// one peer never count toward another peer's download.
+type peerCoverage struct {
+	mu    sync.Mutex
+	peers map[string]*coverage
+}
+
+// of returns the coverage for peer (a peerID), creating it on first use.
+func (p *peerCoverage) of(peer string) *coverage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		p.peers = make(map[string]*coverage)
+	}
+	c, ok := p.peers[peer]
+	if !ok {
+		c = &coverage{}
+		p.peers[peer] = c
+	}
+	return c
+}
+
+// fileETag is a strong validator for the file's current contents.
+func fileETag(fi os.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", fi.Size(), fi.ModTime().UnixNano())
+}
+
+// parseRange parses a single "bytes=" range against a resource of the given
+// size. ok is false when the header should be ignored and the whole file
+// served (absent, malformed or multi-range).
+func parseRange(header string, size int64) (r byteRange, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return byteRange{}, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return byteRange{}, false, nil
+	}
+
+	if first == "" {
+		// Suffix range: the last N bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, false, nil
+		}
+		if n == 0 || size == 0 {
+			return byteRange{}, false, errRangeNotSatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return byteRange{size - n, size}, true, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false, nil
+	}
+	if start >= size {
+		return byteRange{}, false, errRangeNotSatisfiable
+	}
+	end := size
+	if last != "" {
+		e, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || e < start {
+			return byteRange{}, false, nil
+		}
+		if e+1 < size {
+			end = e + 1
+		}
+	}
+	return byteRange{start, end}, true, nil
+}
+
+// ifRangeMatches evaluates an If-Range precondition. An empty header always
+// matches; otherwise it must equal the ETag or the exact Last-Modified date.
+func ifRangeMatches(header, etag string, modTime time.Time) bool {
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, "\"") || strings.HasPrefix(header, "W/") {
+		return header == etag
+	}
+	t, err := http.ParseTime(header)
+	return err == nil && t.Equal(modTime.UTC().Truncate(time.Second))
+}
+
+// setFileHeaders sets the headers shared by HEAD and GET for a single file.
+func setFileHeaders(w http.ResponseWriter, fileName string, fi os.FileInfo) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", fileETag(fi))
+	w.Header().Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+}
+
+// serveFileRange writes content (or the requested range of it) to w and
+// records the bytes that actually went out in cov. content is the file
+// itself, or its ciphertext for encrypted offers; etag and modTime describe
+// the underlying file version.
+func serveFileRange(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, size int64, etag string, modTime time.Time, cov *coverage) error {
+	rng := byteRange{0, size}
+	status := http.StatusOK
+
+	if h := r.Header.Get("Range"); h != "" && ifRangeMatches(r.Header.Get("If-Range"), etag, modTime) {
+		parsed, ok, err := parseRange(h, size)
+		if err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			http.Error(w, "Range Not Satisfiable", http.StatusRequestedRangeNotSatisfiable)
+			return err
+		}
+		if ok {
+			rng = parsed
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rng.start, rng.end-1, size))
+		}
+	}
+
+	if _, err := content.Seek(rng.start, io.SeekStart); err != nil {
+		http.Error(w, "File Error", http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(rng.end-rng.start, 10))
+	w.WriteHeader(status)
+
+	n, err := io.CopyN(w, content, rng.end-rng.start)
+	cov.add(etag, rng.start, rng.start+n)
+	return err
+}
+
+// --- RECEIVER SIDE ---
+
+// partETagPath is the sidecar remembering which version (ETag) of the file
+// a .part download belongs to.
+func partETagPath(partPath string) string {
+	return partPath + ".etag"
+}
+
+// resumeOffset returns how many bytes of etag's version are already in
+// partPath, or 0 if there is nothing usable to continue.
+func resumeOffset(partPath, etag string) int64 {
+	saved, err := os.ReadFile(partETagPath(partPath))
+	if err != nil || etag == "" || string(saved) != etag {
+		return 0
+	}
+	fi, err := os.Stat(partPath)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// contentRangeTotal extracts the complete length from "bytes a-b/total".
+func contentRangeTotal(header string) int64 {
+	_, total, found := strings.Cut(header, "/")
+	if !found {
+		return -1
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,268 @@
+package burn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header string
+		want   byteRange
+		ok     bool
+		err    bool
+	}{
+		{"bytes=0-4", byteRange{0, 5}, true, false},
+		{"bytes=5-", byteRange{5, 10}, true, false},
+		{"bytes=-3", byteRange{7, 10}, true, false},
+		{"bytes=2-100", byteRange{2, 10}, true, false},
+		{"bytes=10-", byteRange{}, false, true},
+		{"bytes=0-1,3-4", byteRange{}, false, false}, // multi-range: serve everything
+		{"items=0-4", byteRange{}, false, false},
+		{"bytes=4-2", byteRange{}, false, false},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseRange(tt.header, 10)
+		if got != tt.want || ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("parseRange(%q) = %v, %v, %v; want %v, %v, err=%v", tt.header, got, ok, err, tt.want, tt.ok, tt.err)
+		}
+	}
+}
+
+func TestCoverageMergesRanges(t *testing.T) {
+	var c coverage
+	c.add("v1", 0, 4)
+	c.add("v1", 6, 10)
+	if c.complete("v1", 10) {
+		t.Fatalf("expected gap at 4-6 to keep coverage incomplete")
+	}
+	c.add("v1", 3, 7)
+	if !c.complete("v1", 10) {
+		t.Fatalf("expected coverage to be complete, got %v", c.ranges)
+	}
+
+	// A new version of the file starts from scratch.
+	c.add("v2", 0, 5)
+	if c.complete("v2", 10) || c.served("v2") != 5 {
+		t.Fatalf("expected coverage reset for new ETag, got %v", c.ranges)
+	}
+}
+
+func TestRegisterHandlersRangeBurnsOnlyWhenCovered(t *testing.T) {
+	oldDelay := browserShutdownDelay
+	browserShutdownDelay = 10 * time.Millisecond
+	defer func() {
+		browserShutdownDelay = oldDelay
+	}()
+
+	filePath := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+
+	shutdownSignal := make(chan string, 1)
+	secretPath := "/secret"
+	mux := http.NewServeMux()
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{filePath}, name: "hello.txt", size: 11},
+		&recipient{target: loginTarget("target@example.com"), secretPath: secretPath, done: shutdownSignal})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(base, rng, ifRange string) *http.Response {
+		req, _ := http.NewRequest("GET", base+secretPath, nil)
+		req.Header.Set("Range", rng)
+		if ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := get(server.URL, "bytes=0-4", "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "hello" {
+		t.Fatalf("expected 206 'hello', got %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 0-4/11" {
+		t.Fatalf("unexpected Content-Range %q", got)
+	}
+	etag := resp.Header.Get("ETag")
+
+	// A stale If-Range gets the whole file instead of a range.
+	resp = get(server.URL, "bytes=5-", "\"stale\"")
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for stale If-Range, got %d", resp.StatusCode)
+	}
+	select {
+	case <-shutdownSignal:
+	case <-time.After(time.Second):
+		t.Fatalf("expected burn once the whole file was served")
+	}
+
+	// Start over with a fresh offer: two halves burn it, one half does not.
+	mux = http.NewServeMux()
+	shutdownSignal = make(chan string, 1)
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{filePath}, name: "hello.txt", size: 11},
+		&recipient{target: loginTarget("target@example.com"), secretPath: secretPath, done: shutdownSignal})
+	server2 := httptest.NewServer(mux)
+	defer server2.Close()
+
+	resp = get(server2.URL, "bytes=0-4", etag)
+	resp.Body.Close()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case reason := <-shutdownSignal:
+		t.Fatalf("unexpected burn after partial range: %s", reason)
+	default:
+	}
+
+	resp = get(server2.URL, "bytes=5-", etag)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != " world" {
+		t.Fatalf("expected ' world', got %q", body)
+	}
+	select {
+	case <-shutdownSignal:
+	case <-time.After(time.Second):
+		t.Fatalf("expected burn once both halves were served")
+	}
+}
+
+func TestReceiveResumesPartFile(t *testing.T) {
+	content := []byte("0123456789abcdef")
+	filePath := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(filePath, content, 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	fi, _ := os.Stat(filePath)
+
+	shutdownSignal := make(chan string, 1)
+	secretPath := "/secret"
+	mux := http.NewServeMux()
+	serveShare(mux, burntest.NewClient("target@example.com", "sender@example.com"),
+		&payload{paths: []string{filePath}, name: "data.bin", size: 16},
+		&recipient{target: loginTarget("target@example.com"), secretPath: secretPath, done: shutdownSignal})
+
+	var gotRange string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			gotRange = r.Header.Get("Range")
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	// Simulate an earlier attempt that died after 6 bytes.
+	dest := t.TempDir()
+	partPath := filepath.Join(dest, "data.bin.part")
+	os.WriteFile(partPath, content[:6], 0644)
+	os.WriteFile(partETagPath(partPath), []byte(fileETag(fi)), 0644)
+
+	if _, err := Receive(context.Background(), server.URL+secretPath, ReceiveOptions{Dir: dest, Resume: true, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if gotRange != "bytes=6-" {
+		t.Fatalf("expected ranged request, got %q", gotRange)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "data.bin"))
+	if err != nil || string(got) != string(content) {
+		t.Fatalf("expected resumed file %q, got %q (%v)", content, got, err)
+	}
+	if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+		t.Fatalf("expected .part to be renamed away")
+	}
+}
+
+func TestReceiveKeepsPartOnInterruption(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"big.bin\"")
+		w.Header().Set("ETag", "\"v1\"")
+		w.Header().Set("Content-Length", "10")
+		fmt.Fprint(w, "12345")
+	}))
+	defer server.Close()
+
+	dest := t.TempDir()
+	if _, err := Receive(context.Background(), server.URL, ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err == nil {
+		t.Fatalf("expected error for short body")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "big.bin.part")); string(got) != "12345" {
+		t.Fatalf("expected partial data kept in .part, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "big.bin")); !os.IsNotExist(err) {
+		t.Fatalf("expected no final file for an incomplete download")
+	}
+}
+
+func TestRegisterHandlersRangeCoverageIsPerIdentity(t *testing.T) {
+	oldDelay := browserShutdownDelay
+	browserShutdownDelay = 10 * time.Millisecond
+	defer func() {
+		browserShutdownDelay = oldDelay
+	}()
+
+	filePath := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+
+	client := &burntest.Client{}
+	shutdownSignal := make(chan string, 1)
+	mux := http.NewServeMux()
+	serveShare(mux, client,
+		&payload{paths: []string{filePath}, name: "hello.txt", size: 11},
+		&recipient{target: anyOf{loginTarget("alice@example.com"), loginTarget("bob@example.com")}, secretPath: "/secret", done: shutdownSignal})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(login, rng string) {
+		client.SetLogin(login)
+		req, _ := http.NewRequest("GET", server.URL+"/secret", nil)
+		req.Header.Set("Range", rng)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			t.Fatalf("expected 206 for %s, got %d", login, resp.StatusCode)
+		}
+	}
+
+	// Each peer fetches half: neither has the file, so nothing burns.
+	get("alice@example.com", "bytes=0-4")
+	get("bob@example.com", "bytes=5-")
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case reason := <-shutdownSignal:
+		t.Fatalf("unexpected burn from halves served to different peers: %s", reason)
+	default:
+	}
+
+	// Once one peer has every byte, the offer burns.
+	get("alice@example.com", "bytes=5-")
+	select {
+	case <-shutdownSignal:
+	case <-time.After(time.Second):
+		t.Fatalf("expected burn once alice had the whole file")
+	}
+}
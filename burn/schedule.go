@@ -0,0 +1,226 @@
+package burn
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- SCHEDULE ---
+// An offer is live from -not-before (default: right away) until -expires.
+// Both take a duration from now (90s, 36h; a bare number is minutes, like
+// the old -timeout) or a time (2026-10-20T18:00Z). Before -not-before the
+// node is up but every download path answers "not yet available"; the
+// inbox and short codes keep working, so the link can be handed out early.
+
+const (
+	expiresHeader   = "X-Tail-Burn-Expires"
+	notBeforeHeader = "X-Tail-Burn-Not-Before"
+)
+
+// whenLayouts are the absolute times -expires and -not-before accept.
+// Without a zone they are local time.
+var whenLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseWhen reads a duration from base or an absolute time.
+func parseWhen(s string, base time.Time) (time.Time, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 {
+			return time.Time{}, fmt.Errorf("%q is not in the future", s)
+		}
+		return base.Add(time.Duration(n) * time.Minute), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("%q is not in the future", s)
+		}
+		return base.Add(d), nil
+	}
+	for _, layout := range whenLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a duration (90s, 36h) nor a time (2026-10-20T18:00Z)", s)
+}
+
+// schedule is when an offer can be downloaded.
+type schedule struct {
+	notBefore time.Time // zero: right away
+	expires   time.Time
+}
+
+// newSchedule resolves the -not-before and -expires flags. A duration in
+// -expires counts from -not-before, so "-not-before=… -expires=1h" is open
+// for an hour.
+func newSchedule(notBefore, expires string, now time.Time) (schedule, error) {
+	var sc schedule
+	var err error
+	if notBefore != "" {
+		if sc.notBefore, err = parseWhen(notBefore, now); err != nil {
+			return sc, fmt.Errorf("-not-before: %w", err)
+		}
+	}
+	if sc.expires, err = parseWhen(expires, sc.opens(now)); err != nil {
+		return sc, fmt.Errorf("-expires: %w", err)
+	}
+	if !sc.expires.After(now) {
+		return sc, fmt.Errorf("-expires: %s has already passed", sc.expires.Format(time.RFC3339))
+	}
+	if !sc.expires.After(sc.opens(now)) {
+		return sc, fmt.Errorf("-expires must be after -not-before")
+	}
+	return sc, nil
+}
+
+// ParseSchedule resolves -not-before and -expires style values (a duration
+// from now, or a time) into an Offer's NotBefore and Expires.
+func ParseSchedule(notBefore, expires string, now time.Time) (time.Time, time.Time, error) {
+	sc, err := newSchedule(notBefore, expires, now)
+	return sc.notBefore, sc.expires, err
+}
+
+// opens is when downloads start, never before now.
+func (sc schedule) opens(now time.Time) time.Time {
+	if sc.notBefore.After(now) {
+		return sc.notBefore
+	}
+	return now
+}
+
+// embargoed reports whether downloads are still closed at now.
+func (sc schedule) embargoed(now time.Time) bool {
+	return now.Before(sc.notBefore)
+}
+
+// guard advertises the expiry on every response and holds back everything
+// but the inbox and code exchange until notBefore.
+func (sc schedule) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(expiresHeader, sc.expires.UTC().Format(time.RFC3339))
+		if !sc.embargoed(time.Now()) || r.URL.Path == offersPath || r.URL.Path == codePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		noCache(w)
+		w.Header().Set(notBeforeHeader, sc.notBefore.UTC().Format(time.RFC3339))
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(sc.notBefore).Seconds())+1))
+		if r.Method != "GET" || r.Header.Get("X-Tail-Burn-Client") != "" {
+			http.Error(w, "Not Yet Available", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = notYetTemplate.Execute(w, countdownData{
+			Opens:   sc.notBefore.UTC().Format(time.RFC3339),
+			Expires: sc.expires.UTC().Format(time.RFC3339),
+		})
+	})
+}
+
+// doomsday signals shutdown at deadline, unless ctx ends first.
+func doomsday(ctx context.Context, deadline time.Time, shutdownSignal chan string) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		select {
+		case shutdownSignal <- ReasonTimeout:
+		default:
+		}
+	case <-ctx.Done():
+	}
+}
+
+// scheduleOf reads the schedule headers from a response (zero if absent).
+func scheduleOf(resp *http.Response) schedule {
+	var sc schedule
+	sc.notBefore, _ = time.Parse(time.RFC3339, resp.Header.Get(notBeforeHeader))
+	sc.expires, _ = time.Parse(time.RFC3339, resp.Header.Get(expiresHeader))
+	return sc
+}
+
+// notYet explains a response from an embargoed offer, or returns nil.
+func notYet(resp *http.Response) error {
+	sc := scheduleOf(resp)
+	if resp.StatusCode != http.StatusServiceUnavailable || sc.notBefore.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("offer is not available yet: it opens at %s (in %s)",
+		sc.notBefore.Local().Format("2006-01-02 15:04:05 MST"), FormatRemaining(time.Until(sc.notBefore)))
+}
+
+// FormatRemaining rounds a countdown for people.
+func FormatRemaining(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	if d >= time.Hour {
+		return d.Round(time.Minute).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// countdownData fills the countdown on the landing and not-yet pages.
+type countdownData struct {
+	Opens, Expires string // RFC 3339
+}
+
+// --- HTML TEMPLATE (Countdown) ---
+// Shared by the landing and not-yet pages: every element with data-until
+// counts down to that time; data-reload reloads the page when it hits zero.
+const countdownHTMLTemplate = `{{define "countdown"}}
+    <script>
+        function tick() {
+            document.querySelectorAll('[data-until]').forEach(function(el) {
+                var s = Math.max(0, Math.ceil((Date.parse(el.dataset.until) - Date.now()) / 1000));
+                var h = Math.floor(s / 3600), m = Math.floor(s % 3600 / 60);
+                el.innerText = (h ? h + 'h ' : '') + (h || m ? m + 'm ' : '') + (s % 60) + 's';
+                if (s === 0 && el.dataset.reload) setTimeout(function() { location.reload(); }, 1000);
+            });
+        }
+        document.addEventListener('DOMContentLoaded', function() {
+            tick();
+            setInterval(tick, 1000);
+        });
+    </script>
+{{end}}`
+
+// --- HTML TEMPLATE (Not Yet Available) ---
+const notYetHTMLTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Tail-Burn</title>
+    <style>
+        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
+        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
+        h1 { font-size: 24px; margin-bottom: 10px; }
+        p { color: #52525b; margin-bottom: 30px; }
+        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
+        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
+    </style>
+    {{template "countdown"}}
+</head>
+<body>
+    <div class="card">
+        <span class="success-icon">⏳</span>
+        <h1>Not Yet Available</h1>
+        <p>This drop opens in <b data-until="{{.Opens}}" data-reload="true">a moment</b>.</p>
+        <div class="footer">This page reloads itself. The link burns {{.Expires}}.</div>
+    </div>
+</body>
+</html>
+`
+
+var notYetTemplate = template.Must(template.Must(template.New("notyet").Parse(notYetHTMLTemplate)).Parse(countdownHTMLTemplate))
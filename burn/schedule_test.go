@@ -0,0 +1,138 @@
+package burn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		in   string
+		want time.Time
+	}{
+		{"10", base.Add(10 * time.Minute)}, // the old -timeout
+		{"90s", base.Add(90 * time.Second)},
+		{"36h", base.Add(36 * time.Hour)},
+		{"2026-10-20T18:00Z", time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC)},
+		{"2026-10-20T18:00:30+02:00", time.Date(2026, 10, 20, 16, 0, 30, 0, time.UTC)},
+		{"2026-10-20T18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, time.Local)},
+	} {
+		got, err := parseWhen(tt.in, base)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseWhen(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "0", "-5m", "tomorrow", "2026-13-01"} {
+		if _, err := parseWhen(bad, base); err == nil {
+			t.Errorf("parseWhen(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestNewSchedule(t *testing.T) {
+	now := time.Now()
+	sc, err := newSchedule("1h", "30m", now)
+	if err != nil {
+		t.Fatalf("newSchedule: %v", err)
+	}
+	// A relative -expires counts from the embargo
+	if !sc.expires.Equal(now.Add(90*time.Minute)) || !sc.embargoed(now) {
+		t.Fatalf("unexpected schedule %+v", sc)
+	}
+	if _, err := newSchedule("", "2001-01-01T00:00Z", now); err == nil {
+		t.Fatalf("expected an expiry in the past to fail")
+	}
+	if _, err := newSchedule(now.Add(2*time.Hour).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339), now); err == nil {
+		t.Fatalf("expected -expires before -not-before to fail")
+	}
+}
+
+func TestDoomsday(t *testing.T) {
+	shutdown := make(chan string, 1)
+	doomsday(context.Background(), time.Now().Add(-time.Second), shutdown)
+	if reason := <-shutdown; reason != "Timeout reached" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doomsday(ctx, time.Now().Add(time.Hour), shutdown)
+	select {
+	case reason := <-shutdown:
+		t.Fatalf("expected a cancelled timer to stay quiet, got %q", reason)
+	default:
+	}
+}
+
+func TestEmbargoedOffer(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "release.key")
+	os.WriteFile(filePath, []byte("launch!"), 0600)
+	sc := schedule{notBefore: time.Now().Add(time.Hour), expires: time.Now().Add(2 * time.Hour)}
+	f := newTestShare(t, behind(sc.guard),
+		withPayload(&payload{paths: []string{filePath}, name: filepath.Base(filePath), size: 7}),
+		withInbox(recipientOffer{target: loginTarget("target@example.com"), info: OfferInfo{Link: "/secret"}}))
+
+	// Browsers get a countdown page
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if !strings.Contains(string(body), "Not Yet Available") || strings.Contains(string(body), "release.key") {
+		t.Fatalf("expected the not-yet page without offer details:\n%s", body)
+	}
+
+	// receive explains when it opens and keeps nothing
+	dest := t.TempDir()
+	_, err = Receive(context.Background(), f.server.URL+"/secret", ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "not available yet") {
+		t.Fatalf("expected an embargo error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing written, got %v", entries)
+	}
+
+	// The inbox still answers, so the offer can be found early
+	resp, err = http.Get(f.server.URL + offersPath)
+	if err != nil {
+		t.Fatalf("GET offers failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the inbox to stay open, got %d", resp.StatusCode)
+	}
+}
+
+func TestScheduledOfferShowsExpiry(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "release.key")
+	os.WriteFile(filePath, []byte("launch!"), 0600)
+	sc := schedule{notBefore: time.Now().Add(-time.Minute), expires: time.Now().Add(time.Hour)}
+	f := newTestShare(t, behind(sc.guard),
+		withPayload(&payload{paths: []string{filePath}, name: filepath.Base(filePath), size: 7}),
+		withInbox(recipientOffer{target: loginTarget("target@example.com"), info: OfferInfo{Link: "/secret"}}))
+
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `data-until="`+sc.expires.UTC().Format(time.RFC3339)+`"`) {
+		t.Fatalf("expected the landing page to count down to the expiry")
+	}
+
+	if _, err := Receive(context.Background(), f.server.URL+"/secret", ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive after the embargo: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package burn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+// --- SENDER VERIFICATION ---
+// receive -from / -from-tag check who is actually answering before anything
+// is saved. Every connection is resolved to a tailnet address, looked up with
+// WhoIs, and only dialed if that node is an ephemeral tail-burn node owned by
+// (or tagged for) the expected sender. Pinning the dial to the verified address means a
+// look-alike hostname can't swap in a different node later.
+
+// DialFunc matches net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// SenderCheck is what the receiver expects of the serving node.
+type SenderCheck struct {
+	Login string               // -from: the node's owner
+	Tag   string               // -from-tag: a tag the node must carry
+	Node  tailcfg.StableNodeID // listen: the very node that pushed the drop
+}
+
+func (c SenderCheck) enabled() bool { return c.Login != "" || c.Tag != "" || c.Node != "" }
+
+// verify checks a WhoIs result for the serving node.
+func (c SenderCheck) verify(who *apitype.WhoIsResponse) error {
+	if who == nil || who.Node == nil {
+		return fmt.Errorf("server is not a node on this tailnet")
+	}
+	if name := nodeHostname(who); !strings.HasPrefix(name, "tail-burn-") {
+		return fmt.Errorf("server %q is not a tail-burn node", name)
+	}
+	if !who.Node.Hostinfo.Valid() || who.Node.Hostinfo.App() != ephemeralApp {
+		return fmt.Errorf("server %q is not an ephemeral tail-burn node", nodeHostname(who))
+	}
+	if c.Login != "" {
+		if who.UserProfile == nil || !strings.EqualFold(who.UserProfile.LoginName, c.Login) {
+			return fmt.Errorf("server belongs to %s, not %s", peerName(who), c.Login)
+		}
+	}
+	if c.Tag != "" && !slices.Contains(who.Node.Tags, c.Tag) {
+		return fmt.Errorf("server %s is not tagged %s", peerName(who), c.Tag)
+	}
+	if c.Node != "" && who.Node.StableID != c.Node {
+		return fmt.Errorf("server %s is not the node that pushed the drop", peerName(who))
+	}
+	return nil
+}
+
+// nodeHostname is the hostname a node registered with.
+func nodeHostname(who *apitype.WhoIsResponse) string {
+	if who.Node.Hostinfo.Valid() && who.Node.Hostinfo.Hostname() != "" {
+		return who.Node.Hostinfo.Hostname()
+	}
+	short, _, _ := strings.Cut(who.Node.Name, ".")
+	return short
+}
+
+// verifiedDialer wraps dial so it only connects to a node that passes check,
+// noting the verified sender on status.
+func verifiedDialer(lc LocalClient, check SenderCheck, dial DialFunc, status io.Writer) DialFunc {
+	var once sync.Once
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := tailnetAddrs(ctx, lc, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range ips {
+			who, err := lc.WhoIs(ctx, ip)
+			if err != nil {
+				lastErr = fmt.Errorf("cannot verify sender: %w", err)
+				continue
+			}
+			if err := check.verify(who); err != nil {
+				lastErr = refusal{fmt.Errorf("refusing to download: %w", err)}
+				continue
+			}
+			once.Do(func() { fmt.Fprintf(status, "🪪 Verified sender: %s (%s)\n", peerName(who), ip) })
+			return dial(ctx, network, net.JoinHostPort(ip, port))
+		}
+		return nil, lastErr
+	}
+}
+
+// tailnetAddrs resolves host from the tailnet's own peer list, so a
+// hijacked DNS answer can't pick the node. Names that aren't peers fall back
+// to the system resolver (WhoIs still has the final say).
+func tailnetAddrs(ctx context.Context, lc LocalClient, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	if st, err := lc.Status(ctx); err == nil {
+		for _, peer := range st.Peer {
+			fqdn := strings.TrimSuffix(peer.DNSName, ".")
+			short, _, _ := strings.Cut(fqdn, ".")
+			if !strings.EqualFold(host, fqdn) && !strings.EqualFold(host, short) {
+				continue
+			}
+			var ips []string
+			for _, ip := range peer.TailscaleIPs {
+				ips = append(ips, ip.String())
+			}
+			return ips, nil
+		}
+	}
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+// transferClient is the HTTP client Receive and Fulfill talk to the server
+// with: stall-guarded, over opts.Dial, and pinned to a verified sender when
+// opts.From is set.
+func transferClient(opts ReceiveOptions) (*http.Client, error) {
+	dial := opts.Dial
+	if opts.From.enabled() {
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+		}
+		dial = verifiedDialer(opts.localClient(), opts.From, dial, opts.status())
+	}
+	return newStallClient(opts.Stall, dial), nil
+}
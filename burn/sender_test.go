@@ -0,0 +1,114 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tail-burn/burn/burntest"
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+)
+
+func burnNode(name, login string, tags ...string) *apitype.WhoIsResponse {
+	who := &apitype.WhoIsResponse{
+		Node:        &tailcfg.Node{Name: name + ".tailnet.ts.net.", StableID: "nBURN", Tags: tags},
+		UserProfile: &tailcfg.UserProfile{LoginName: login},
+	}
+	who.Node.Hostinfo = (&tailcfg.Hostinfo{App: ephemeralApp}).View()
+	return who
+}
+
+func TestSenderCheckVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		check SenderCheck
+		who   *apitype.WhoIsResponse
+		ok    bool
+	}{
+		{"owner matches", SenderCheck{Login: "alice@example.com"}, burnNode("tail-burn-ab12", "Alice@example.com"), true},
+		{"wrong owner", SenderCheck{Login: "alice@example.com"}, burnNode("tail-burn-ab12", "mallory@example.com"), false},
+		{"look-alike host", SenderCheck{Login: "alice@example.com"}, burnNode("tail-bum-ab12", "alice@example.com"), false},
+		{"not ephemeral", SenderCheck{Login: "alice@example.com"}, &apitype.WhoIsResponse{
+			Node:        &tailcfg.Node{Name: "tail-burn-ab12.tailnet.ts.net.", StableID: "nBURN"},
+			UserProfile: &tailcfg.UserProfile{LoginName: "alice@example.com"},
+		}, false},
+		{"not a node", SenderCheck{Login: "alice@example.com"}, &apitype.WhoIsResponse{}, false},
+		{"tag matches", SenderCheck{Tag: "tag:burn"}, burnNode("tail-burn-ab12", "tagged-devices", "tag:burn"), true},
+		{"tag missing", SenderCheck{Tag: "tag:burn"}, burnNode("tail-burn-ab12", "tagged-devices", "tag:ci"), false},
+	}
+	for _, tt := range tests {
+		err := tt.check.verify(tt.who)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: verify = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestReceiveFromVerifiedSender(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf"}))
+
+	dest := t.TempDir()
+	opts := ReceiveOptions{
+		Dir:    dest,
+		Stall:  DefaultStallPolicy,
+		From:   SenderCheck{Login: "alice@example.com"},
+		Client: burntest.NewPeerClient(burnNode("tail-burn-ab12", "alice@example.com")),
+	}
+	if _, err := Receive(context.Background(), f.server.URL+"/secret", opts); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "plans.pdf")); err != nil {
+		t.Fatalf("expected file from verified sender: %v", err)
+	}
+}
+
+func TestReceiveRefusesUnexpectedSender(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	dest := t.TempDir()
+	opts := ReceiveOptions{
+		Dir:    dest,
+		Stall:  DefaultStallPolicy,
+		From:   SenderCheck{Login: "alice@example.com"},
+		Client: burntest.NewPeerClient(burnNode("tail-burn-ab12", "mallory@example.com")),
+	}
+	_, err := Receive(context.Background(), server.URL+"/secret", opts)
+	if err == nil || !strings.Contains(err.Error(), "not alice@example.com") {
+		t.Fatalf("expected sender mismatch, got %v", err)
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected a sender mismatch to be ErrForbidden, got %v", err)
+	}
+	if hits != 0 {
+		t.Fatalf("expected no request to reach an unverified server, got %d", hits)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing saved, got %v", entries)
+	}
+}
+
+func TestTailnetAddrsUsesPeerList(t *testing.T) {
+	lc := &burntest.Client{Peers: []*ipnstate.PeerStatus{
+		{DNSName: "tail-burn-ab12.tailnet.ts.net.", TailscaleIPs: []netip.Addr{netip.MustParseAddr("100.64.0.7")}},
+	}}
+	for _, host := range []string{"tail-burn-ab12", "tail-burn-ab12.tailnet.ts.net", "100.64.0.7"} {
+		ips, err := tailnetAddrs(context.Background(), lc, host)
+		if err != nil || len(ips) != 1 || ips[0] != "100.64.0.7" {
+			t.Fatalf("%s: got %v, %v", host, ips, err)
+		}
+	}
+}
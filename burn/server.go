@@ -0,0 +1,578 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tsnet"
+)
+
+// --- SERVER ---
+// A Server hosts any number of offers and upload requests on one node. Every
+// share gets its own unguessable path with its own handlers; the inbox, the
+// short code exchange and pushes are shared. A burned offer is unmounted, so
+// nothing keeps its payload or key alive; its paths, like any unknown one,
+// answer 410 Gone.
+
+// LocalClient is the part of a Tailscale local client the server uses to
+// identify peers: a tsnet.Server's LocalClient, or the system tailscaled's.
+type LocalClient interface {
+	WhoIs(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error)
+	Status(ctx context.Context) (*ipnstate.Status, error)
+}
+
+// Server hosts offers. Set Client before adding any; the callbacks are
+// optional and may be called from several goroutines at once.
+type Server struct {
+	Client     LocalClient
+	BaseURL    string      // scheme://host links point at; ListenTailnet sets it
+	Stall      StallPolicy // aborts transfers that stop moving (Serve only)
+	GuardReads bool        // stall-guard reads too, for large uploads (AddRequest)
+
+	Logf  func(format string, args ...any) // nil: log.Printf
+	Audit *AuditLog                        // records offers, requests and burns (nil: none)
+
+	OnBlocked   func(h *Hosted, p Peer)                    // a peer the target turned away
+	OnTransfer  func(h *Hosted, p Peer)                    // a download or reveal started
+	OnProgress  func(h *Hosted, p Peer, sent, total int64) // total is -1 if unknown
+	OnAck       func(h *Hosted, p Peer)                    // a receiver confirmed receipt
+	OnCollected func(h *Hosted, recipient, reason string)  // one share of a per-recipient offer finished
+	OnBurn      func(h *Hosted, reason string)             // the offer is gone
+
+	setup   sync.Once
+	mu      sync.Mutex
+	mux     *http.ServeMux
+	routes  map[string]route // the share paths of unburned offers and requests
+	hosted  []*Hosted
+	code    http.Handler // the exchange for the offer with a short code
+	coded   *Hosted
+	servers []*http.Server
+}
+
+func (s *Server) init() {
+	s.setup.Do(func() {
+		s.mux = http.NewServeMux()
+		s.routes = make(map[string]route)
+		s.mux.HandleFunc("/", s.serveRoute)
+		s.registerInboxHandler(s.mux, s.offers)
+		s.mux.HandleFunc(codePath, func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			code := s.code
+			s.mu.Unlock()
+			if code == nil {
+				http.NotFound(w, r)
+				return
+			}
+			code.ServeHTTP(w, r)
+		})
+	})
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// blocked logs and reports a peer the target turned away.
+func (s *Server) blocked(h *Hosted, who *apitype.WhoIsResponse) {
+	s.logf("⛔️ BLOCKED: %s", peerName(who))
+	if s.OnBlocked != nil {
+		s.OnBlocked(h, peerOf(who))
+	}
+}
+
+func (s *Server) transferStarted(h *Hosted, who *apitype.WhoIsResponse) {
+	s.auditPeer("transfer", h, peerOf(who))
+	if s.OnTransfer != nil {
+		s.OnTransfer(h, peerOf(who))
+	}
+}
+
+// trackProgress wraps w to report how much of total (-1: unknown) went out.
+// finish reports the last bytes.
+func (s *Server) trackProgress(w http.ResponseWriter, h *Hosted, who *apitype.WhoIsResponse, total int64) (_ http.ResponseWriter, finish func()) {
+	if s.OnProgress == nil {
+		return w, func() {}
+	}
+	peer := peerOf(who)
+	pw := &progressWriter{ResponseWriter: w, last: time.Now(), report: func(n int64) {
+		s.OnProgress(h, peer, n, total)
+	}}
+	return pw, pw.finish
+}
+
+// serveRoute dispatches to the share mounted at the request's path.
+func (s *Server) serveRoute(w http.ResponseWriter, r *http.Request) {
+	rt, ok := s.route(r.URL.Path)
+	if !ok {
+		gone(w, r)
+		return
+	}
+	rt.handler.ServeHTTP(w, r)
+}
+
+// route looks up the share mounted at path.
+func (s *Server) route(path string) (route, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.routes[path]
+	return rt, ok
+}
+
+// ServeHTTP serves the hosted offers, for callers with their own http.Server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.init()
+	if s.Audit != nil {
+		s.auditRequest(w, r)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenTailnet waits for ts to join its tailnet and listens on it: HTTPS
+// with the node's certificate when the tailnet has them (port 80 then only
+// redirects), plain HTTP inside WireGuard otherwise. It sets BaseURL.
+func (s *Server) ListenTailnet(ctx context.Context, ts *tsnet.Server) (net.Listener, error) {
+	ln, redirect, base, err := listenTailnet(ctx, ts, ts.Hostname, s.logf)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.BaseURL = base
+	if redirect != nil {
+		s.servers = append(s.servers, redirect)
+	}
+	return ln, nil
+}
+
+// Serve answers requests on ln until Shutdown.
+func (s *Server) Serve(ln net.Listener) error {
+	s.init()
+	ln = &stallListener{Listener: ln, policy: s.Stall, guardReads: s.GuardReads}
+	// No WriteTimeout: big transfers may take hours. Each connection is
+	// instead aborted only when it stops moving (see stall.go).
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	if !s.GuardReads {
+		srv.ReadTimeout = 30 * time.Second
+	}
+	s.mu.Lock()
+	s.servers = append(s.servers, srv)
+	s.mu.Unlock()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops serving, waiting for running transfers until ctx ends. It
+// doesn't burn anything; offers that should not outlive it must be burned
+// first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	servers := s.servers
+	s.servers = nil
+	s.mu.Unlock()
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Hosted returns the offers and requests that haven't burned yet.
+func (s *Server) Hosted() []*Hosted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var live []*Hosted
+	for _, h := range s.hosted {
+		if !h.burned.Load() {
+			live = append(live, h)
+		}
+	}
+	return live
+}
+
+// offers lists the live offers for the inbox.
+func (s *Server) offers() []recipientOffer {
+	var offers []recipientOffer
+	for _, h := range s.Hosted() {
+		offers = append(offers, h.offers...)
+	}
+	return offers
+}
+
+// Add hosts an offer until it burns: once it has been collected, at
+// Expires, or when Burn is called.
+func (s *Server) Add(o *Offer) (*Hosted, error) {
+	s.init()
+	if err := o.Prepare(); err != nil {
+		return nil, err
+	}
+	p := o.p
+	if o.Expires.IsZero() {
+		return nil, fmt.Errorf("an offer needs Expires")
+	}
+	if !o.Expires.After(time.Now()) || !o.Expires.After(o.NotBefore) {
+		return nil, fmt.Errorf("offer expires at %s, before it opens", o.Expires.Format(time.RFC3339))
+	}
+	attempts := o.CodeAttempts
+	if attempts <= 0 {
+		attempts = DefaultCodeAttempts
+	}
+
+	h := newHosted(s, o.Info())
+	h.perRecipient = len(o.Recipients) > 0
+	if h.perRecipient {
+		recipients, err := newRecipients(o.Recipients, o.ACL, p.size, p.class)
+		if err != nil {
+			return nil, err
+		}
+		h.recipients = recipients
+	} else {
+		target, err := withGrant(o.Target, o.ACL, p.size, p.class)
+		if err != nil {
+			return nil, err
+		}
+		secretPath, err := newSecretPath()
+		if err != nil {
+			return nil, err
+		}
+		h.recipients = []*recipient{{target: target, secretPath: secretPath, done: h.signal}}
+	}
+	h.digest = p.linkDigest
+	h.key = p.key
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o.Code != "" && s.coded != nil && !s.coded.burned.Load() {
+		return nil, fmt.Errorf("the server already hosts an offer with a short code")
+	}
+
+	sched := schedule{notBefore: o.NotBefore, expires: o.Expires}
+	for _, r := range h.recipients {
+		r.hosted = h
+		mux := http.NewServeMux()
+		if p.secret != nil {
+			// Each share zeroes its own copy once burned
+			share := *p
+			if h.perRecipient {
+				share.secret = bytes.Clone(p.secret)
+			}
+			h.zeroize = append(h.zeroize, s.registerTextHandlers(mux, &share, r))
+		} else {
+			s.registerHandlers(mux, p, r)
+		}
+		s.mount(h, sched.guard(mux), r.secretPath, r.ackPath())
+		h.offers = append(h.offers, recipientOffer{target: r.target, info: OfferInfo{
+			Name:      p.name,
+			Size:      p.size,
+			Class:     p.class,
+			NotBefore: o.NotBefore,
+			Expires:   o.Expires,
+			Encrypted: p.key != nil,
+			Link:      buildLink(r.secretPath, linkSecrets{digest: p.linkDigest, key: p.key}),
+		}})
+	}
+	if p.secret != nil {
+		h.zeroize = append(h.zeroize, func() { clear(p.secret) })
+	}
+	if h.perRecipient {
+		h.shares.track(h.recipients, h.signal, func(r *recipient, reason string) {
+			s.audit(AuditEntry{Event: "collected", Offer: h.ID(), Name: h.info.Name, Target: r.target.String(), Reason: reason})
+			if s.OnCollected != nil {
+				s.OnCollected(h, r.target.String(), reason)
+			}
+		})
+	}
+	if o.Code != "" {
+		s.code = h.gate(s.codeHandler(h, h.offers, o.Code, attempts, h.signal))
+		s.coded = h
+	}
+	s.hosted = append(s.hosted, h)
+	s.audit(AuditEntry{
+		Event:     "offer_created",
+		Offer:     h.ID(),
+		Name:      p.name,
+		Size:      p.size,
+		Digest:    p.browserDigest,
+		Target:    h.target(),
+		NotBefore: o.NotBefore,
+		Expires:   o.Expires,
+	})
+	go h.run(o.Expires)
+	return h, nil
+}
+
+// Request is a one-time upload endpoint: the reverse of an offer.
+type Request struct {
+	From    Authorizer // who may upload
+	Dir     string     // where the upload is saved
+	Expires time.Time  // the request burns then
+}
+
+// AddRequest hosts an upload request until one file has arrived, Expires
+// passes, or Burn is called.
+func (s *Server) AddRequest(req Request) (*Hosted, error) {
+	s.init()
+	if req.From == nil {
+		return nil, fmt.Errorf("a request needs From")
+	}
+	if fi, err := os.Stat(req.Dir); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("destination is not a directory: %s", req.Dir)
+	}
+	if !req.Expires.After(time.Now()) {
+		return nil, fmt.Errorf("request expires at %s, which has passed", req.Expires.Format(time.RFC3339))
+	}
+	secretPath, err := newSecretPath()
+	if err != nil {
+		return nil, err
+	}
+	h := newHosted(s, OfferInfo{Expires: req.Expires})
+	r := &recipient{target: req.From, secretPath: secretPath, done: h.signal, hosted: h}
+	h.recipients = []*recipient{r}
+
+	mux := http.NewServeMux()
+	s.registerUploadHandlers(mux, req.Dir, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mount(h, mux, secretPath)
+	s.hosted = append(s.hosted, h)
+	s.audit(AuditEntry{Event: "request_created", Offer: h.ID(), Target: h.target(), Expires: req.Expires})
+	go h.run(req.Expires)
+	return h, nil
+}
+
+// HandlePushes accepts offers pushed by peers allow lets through (see Push)
+// and hands each link to accept in its own goroutine.
+func (s *Server) HandlePushes(allow Authorizer, accept func(from Peer, link string)) {
+	s.init()
+	s.registerPushHandler(s.mux, allow, accept)
+}
+
+// route is a share path's handler and the offer or request it belongs to.
+type route struct {
+	hosted  *Hosted
+	handler http.Handler
+}
+
+// mount serves next at paths until h burns. Callers hold s.mu.
+func (s *Server) mount(h *Hosted, next http.Handler, paths ...string) {
+	rt := route{hosted: h, handler: h.gate(next)}
+	for _, path := range paths {
+		s.routes[path] = rt
+	}
+	h.paths = append(h.paths, paths...)
+}
+
+// Hosted is an offer or request a Server is hosting.
+type Hosted struct {
+	srv          *Server
+	id           string
+	info         OfferInfo
+	digest       string // what the smart client verifies
+	key          []byte
+	recipients   []*recipient
+	perRecipient bool
+	offers       []recipientOffer
+	shares       fanout
+	zeroize      []func()
+	paths        []string // where the Server mounted it
+
+	signal chan string // the first reason to burn wins
+	done   chan struct{}
+	burned atomic.Bool
+	reason string // set before done is closed
+}
+
+func newHosted(s *Server, info OfferInfo) *Hosted {
+	id := make([]byte, 4)
+	rand.Read(id) // never fails
+	return &Hosted{srv: s, id: hex.EncodeToString(id), info: info, signal: make(chan string, 1), done: make(chan struct{})}
+}
+
+// run burns h on the first shutdown signal, or at its deadline.
+func (h *Hosted) run(deadline time.Time) {
+	timerCtx, stopTimer := context.WithCancel(context.Background())
+	go doomsday(timerCtx, deadline, h.signal)
+	reason := <-h.signal
+	stopTimer()
+	h.burned.Store(true)
+	for _, zeroize := range h.zeroize {
+		zeroize()
+	}
+	// Unmount it, so its handlers (and the payload, key and tokens they
+	// hold) can go; its paths answer 410 from now on
+	h.srv.mu.Lock()
+	for _, path := range h.paths {
+		delete(h.srv.routes, path)
+	}
+	if h.srv.coded == h {
+		h.srv.code = http.HandlerFunc(gone)
+	}
+	h.srv.mu.Unlock()
+	h.reason = reason
+	close(h.done)
+	h.srv.audit(AuditEntry{Event: "burned", Offer: h.id, Name: h.info.Name, Reason: reason})
+	h.srv.mu.Lock()
+	h.srv.hosted = slices.DeleteFunc(h.srv.hosted, func(o *Hosted) bool { return o == h })
+	h.srv.mu.Unlock()
+	if h.srv.OnBurn != nil {
+		h.srv.OnBurn(h, reason)
+	}
+}
+
+// ID tells the offer apart from the others on its server, in the audit
+// log and the daemon.
+func (h *Hosted) ID() string { return h.id }
+
+// Info describes the offer (Name is empty for a request).
+func (h *Hosted) Info() OfferInfo { return h.info }
+
+// target lists who may collect the offer.
+func (h *Hosted) target() string {
+	var terms []string
+	for _, r := range h.recipients {
+		terms = append(terms, r.target.String())
+	}
+	return strings.Join(terms, ", ")
+}
+
+// Link is where one recipient collects an offer.
+type Link struct {
+	Recipient  string `json:"recipient"`  // who may collect through it
+	URL        string `json:"url"`        // for tail-burn receive: the digest and key are in the #fragment
+	BrowserURL string `json:"browserUrl"` // for browsers, which show the digest instead
+}
+
+// Links lists where the offer (or request) can be collected: one link per
+// recipient.
+func (h *Hosted) Links() []Link {
+	h.srv.mu.Lock()
+	base := h.srv.BaseURL
+	h.srv.mu.Unlock()
+	var links []Link
+	for _, r := range h.recipients {
+		url := base + r.secretPath
+		links = append(links, Link{
+			Recipient:  r.target.String(),
+			URL:        buildLink(url, linkSecrets{digest: h.digest, key: h.key}),
+			BrowserURL: buildLink(url, linkSecrets{key: h.key}),
+		})
+	}
+	return links
+}
+
+// Done is closed once the offer has burned.
+func (h *Hosted) Done() <-chan struct{} { return h.done }
+
+// Reason is why the offer burned ("" until Done is closed).
+func (h *Hosted) Reason() string {
+	select {
+	case <-h.done:
+		return h.reason
+	default:
+		return ""
+	}
+}
+
+// Burn burns the offer now, unless it already has, and waits until it has.
+func (h *Hosted) Burn(reason string) {
+	select {
+	case h.signal <- reason:
+	default:
+	}
+	<-h.done
+}
+
+// Collected lists which recipients of an offer with Recipients have (and
+// haven't) collected.
+func (h *Hosted) Collected() (got, pending []string) {
+	if !h.perRecipient {
+		return nil, nil
+	}
+	return h.shares.status(h.recipients)
+}
+
+// gate answers 410 Gone once h has burned.
+func (h *Hosted) gate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.burned.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gone(w, r)
+	})
+}
+
+// gone answers 410 Gone: with the burned page for a browser.
+func gone(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Tail-Burn-Client") == "true" || r.Method != "GET" {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+	w.WriteHeader(http.StatusGone)
+	_ = burnedTemplate.Execute(w, nil)
+}
+
+// progressInterval is how often progress is reported.
+const progressInterval = time.Second
+
+// progressWriter reports how much of a response went out.
+type progressWriter struct {
+	http.ResponseWriter
+	report   func(n int64)
+	n        int64
+	reported int64
+	last     time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.ResponseWriter.Write(b)
+	p.n += int64(n)
+	if time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.reported = p.n
+		p.report(p.n)
+	}
+	return n, err
+}
+
+func (p *progressWriter) finish() {
+	if p.n != p.reported {
+		p.reported = p.n
+		p.report(p.n)
+	}
+}
+
+func (p *progressWriter) Flush() {
+	if f, ok := p.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (p *progressWriter) Unwrap() http.ResponseWriter { return p.ResponseWriter }
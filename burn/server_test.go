@@ -0,0 +1,276 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tail-burn/burn/burntest"
+)
+
+// newTestServer hosts a Server over plain HTTP, identifying peers with client.
+func newTestServer(t *testing.T, client LocalClient) *Server {
+	t.Helper()
+	srv := &Server{Client: client}
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	srv.BaseURL = server.URL
+	return srv
+}
+
+func writeOfferFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plans.pdf")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return path
+}
+
+func TestServerHostsOfferUntilCollected(t *testing.T) {
+	client := &burntest.Client{}
+	client.SetLogin("target@example.com")
+	srv := newTestServer(t, client)
+
+	var mu sync.Mutex
+	var seen []string
+	record := func(what string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, what)
+	}
+	srv.OnTransfer = func(h *Hosted, p Peer) { record("transfer " + p.Name) }
+	srv.OnAck = func(h *Hosted, p Peer) { record("ack " + p.Name) }
+	burned := make(chan string, 1)
+	srv.OnBurn = func(h *Hosted, reason string) { burned <- reason }
+
+	h, err := srv.Add(&Offer{
+		Paths:   []string{writeOfferFile(t, "top secret plans")},
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if live := srv.Hosted(); len(live) != 1 || live[0] != h {
+		t.Fatalf("expected the offer to be hosted, got %v", live)
+	}
+	links := h.Links()
+	if len(links) != 1 || !strings.HasPrefix(links[0].URL, srv.BaseURL+"/") || !strings.Contains(links[0].URL, "#sha256=") {
+		t.Fatalf("unexpected links %+v", links)
+	}
+	if links[0].Recipient != "target@example.com" {
+		t.Fatalf("expected the target as recipient, got %q", links[0].Recipient)
+	}
+
+	dest := t.TempDir()
+	res, err := Receive(context.Background(), links[0].URL, ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if !res.Burned || len(res.Paths) != 1 || res.Digest == "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if got, _ := os.ReadFile(res.Paths[0]); string(got) != "top secret plans" {
+		t.Fatalf("expected the file, got %q", got)
+	}
+
+	select {
+	case reason := <-burned:
+		if reason != ReasonAck {
+			t.Fatalf("unexpected burn reason %q", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the offer to burn")
+	}
+	if h.Reason() != ReasonAck || len(srv.Hosted()) != 0 {
+		t.Fatalf("expected a burned offer to be gone, reason %q, hosted %v", h.Reason(), srv.Hosted())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(seen, []string{"transfer target@example.com", "ack target@example.com"}) {
+		t.Fatalf("unexpected callbacks %v", seen)
+	}
+}
+
+func TestServerBurnAnswersGone(t *testing.T) {
+	client := &burntest.Client{}
+	client.SetLogin("target@example.com")
+	srv := newTestServer(t, client)
+	h, err := srv.Add(&Offer{
+		Text:    []byte("hunter2"),
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	h.Burn("Revoked")
+	if h.Reason() != "Revoked" {
+		t.Fatalf("expected the reason Burn was given, got %q", h.Reason())
+	}
+	_, err = Receive(context.Background(), h.Links()[0].URL, ReceiveOptions{Stall: DefaultStallPolicy})
+	if !errors.Is(err, ErrGone) {
+		t.Fatalf("expected a burned offer to be gone, got %v", err)
+	}
+	resp, err := http.Get(h.Links()[0].BrowserURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected 410 for a browser, got %d", resp.StatusCode)
+	}
+
+	// Nothing of the burned offer stays mounted
+	srv.mu.Lock()
+	mounted := len(srv.routes)
+	srv.mu.Unlock()
+	if mounted != 0 {
+		t.Fatalf("expected the burned offer to be unmounted, %d routes left", mounted)
+	}
+	resp, err = http.Get(srv.BaseURL + "/never-issued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected 410 for an unknown path, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerExpiresOffer(t *testing.T) {
+	srv := newTestServer(t, burntest.NewClient("target@example.com", ""))
+	h, err := srv.Add(&Offer{
+		Paths:   []string{writeOfferFile(t, "top secret plans")},
+		Target:  loginTarget("target@example.com"),
+		Expires: time.Now().Add(50 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	select {
+	case <-h.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the offer to burn at Expires")
+	}
+	if h.Reason() != ReasonTimeout {
+		t.Fatalf("unexpected reason %q", h.Reason())
+	}
+}
+
+func TestServerPerRecipientOffer(t *testing.T) {
+	client := &burntest.Client{}
+	srv := newTestServer(t, client)
+	collected := make(chan string, 2)
+	srv.OnCollected = func(h *Hosted, recipient, reason string) { collected <- recipient }
+
+	h, err := srv.Add(&Offer{
+		Paths:      []string{writeOfferFile(t, "release bundle")},
+		Recipients: []Authorizer{loginTarget("alice@example.com"), loginTarget("bob@example.com")},
+		Expires:    time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	links := h.Links()
+	if len(links) != 2 || links[0].URL == links[1].URL {
+		t.Fatalf("expected a link per recipient, got %+v", links)
+	}
+
+	client.SetLogin("alice@example.com")
+	if _, err := Receive(context.Background(), links[0].URL, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("alice: %v", err)
+	}
+	if who := <-collected; who != "alice@example.com" {
+		t.Fatalf("expected alice to collect, got %s", who)
+	}
+	if got, pending := h.Collected(); len(got) != 1 || len(pending) != 1 || h.Reason() != "" {
+		t.Fatalf("expected the offer to wait for bob: collected %v, pending %v, reason %q", got, pending, h.Reason())
+	}
+
+	client.SetLogin("bob@example.com")
+	if _, err := Receive(context.Background(), links[1].URL, ReceiveOptions{Dir: t.TempDir(), Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("bob: %v", err)
+	}
+	select {
+	case <-h.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the offer to burn once everyone collected")
+	}
+	if h.Reason() != ReasonCollected {
+		t.Fatalf("unexpected reason %q", h.Reason())
+	}
+}
+
+func TestServerOneCodeOffer(t *testing.T) {
+	srv := newTestServer(t, burntest.NewClient("target@example.com", ""))
+	offer := func() *Offer {
+		return &Offer{
+			Paths:   []string{writeOfferFile(t, "top secret plans")},
+			Target:  loginTarget("target@example.com"),
+			Expires: time.Now().Add(time.Minute),
+			Code:    "7-crossword-lantern",
+		}
+	}
+	first, err := srv.Add(offer())
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := srv.Add(offer()); err == nil {
+		t.Fatalf("expected a second code offer to be refused")
+	}
+	first.Burn("Revoked")
+	if _, err := srv.Add(offer()); err != nil {
+		t.Fatalf("expected a code offer once the first burned: %v", err)
+	}
+}
+
+func TestServerAddChecksOffer(t *testing.T) {
+	srv := newTestServer(t, burntest.NewClient("target@example.com", ""))
+	path := writeOfferFile(t, "top secret plans")
+	for name, o := range map[string]*Offer{
+		"no expiry":   {Paths: []string{path}, Target: loginTarget("target@example.com")},
+		"expired":     {Paths: []string{path}, Target: loginTarget("target@example.com"), Expires: time.Now().Add(-time.Minute)},
+		"no target":   {Paths: []string{path}, Expires: time.Now().Add(time.Minute)},
+		"two sources": {Paths: []string{path}, Text: []byte("x"), Target: loginTarget("target@example.com"), Expires: time.Now().Add(time.Minute)},
+	} {
+		if _, err := srv.Add(o); err == nil {
+			t.Errorf("%s: expected Add to fail", name)
+		}
+	}
+	if len(srv.Hosted()) != 0 {
+		t.Fatalf("expected nothing hosted, got %v", srv.Hosted())
+	}
+}
+
+func TestServerRequest(t *testing.T) {
+	client := &burntest.Client{}
+	client.SetLogin("colleague@example.com")
+	srv := newTestServer(t, client)
+	dest := t.TempDir()
+	h, err := srv.AddRequest(Request{From: loginTarget("colleague@example.com"), Dir: dest, Expires: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("AddRequest: %v", err)
+	}
+	path := writeOfferFile(t, "log bundle")
+	if err := Fulfill(context.Background(), h.Links()[0].URL, path, ReceiveOptions{Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("Fulfill: %v", err)
+	}
+	<-h.Done()
+	if h.Reason() != ReasonUpload {
+		t.Fatalf("unexpected reason %q", h.Reason())
+	}
+	if got, _ := os.ReadFile(filepath.Join(dest, "plans.pdf")); string(got) != "log bundle" {
+		t.Fatalf("expected the upload saved, got %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- STALL DETECTION ---
+// Transfers may take as long as they need, as long as they keep moving.
+// Instead of a fixed deadline for the whole response, every connection gets
+// a rolling deadline that is pushed forward whenever bytes move, plus an
+// optional floor on the average rate of each burst of activity.
+
+// DefaultStallPolicy gives up on a transfer after 30 seconds without progress.
+var DefaultStallPolicy = StallPolicy{Idle: 30 * time.Second}
+
+// StallPolicy decides when a transfer counts as dead.
+type StallPolicy struct {
+	Idle    time.Duration // abort when no byte moves for this long (0 = never)
+	MinRate int64         // minimum average bytes/s once Idle has elapsed (0 = off)
+}
+
+func (p StallPolicy) String() string {
+	if p.Idle <= 0 {
+		return "off"
+	}
+	if p.MinRate > 0 {
+		return fmt.Sprintf("%s idle, %s/s min", p.Idle, FormatBytes(p.MinRate))
+	}
+	return fmt.Sprintf("%s idle", p.Idle)
+}
+
+// check returns an error when a burst that started at start and has moved n
+// bytes so far is running below MinRate.
+func (p StallPolicy) check(start time.Time, n int64) error {
+	if p.MinRate <= 0 || p.Idle <= 0 {
+		return nil
+	}
+	elapsed := time.Since(start)
+	if elapsed < p.Idle {
+		return nil // grace period: let TCP ramp up
+	}
+	if rate := float64(n) / elapsed.Seconds(); rate < float64(p.MinRate) {
+		return &stallError{fmt.Sprintf("transfer too slow: %s/s is below the %s/s minimum", FormatBytes(int64(rate)), FormatBytes(p.MinRate))}
+	}
+	return nil
+}
+
+type stallError struct{ msg string }
+
+func (e *stallError) Error() string { return e.msg }
+
+// stallConn enforces a StallPolicy on a connection. Servers guard writes
+// (the http.Server manages read deadlines itself) and, when accepting
+// uploads, reads; clients guard both.
+type stallConn struct {
+	net.Conn
+	policy      StallPolicy
+	guardReads  bool
+	guardWrites bool
+
+	mu         sync.Mutex // clients read and write from different goroutines
+	burstStart time.Time
+	burstBytes int64
+	lastMove   time.Time
+}
+
+// track accounts for n moved bytes. A gap longer than Idle (e.g. between
+// keep-alive requests) starts a new burst so idle time isn't held against
+// the next transfer.
+func (c *stallConn) track(before time.Time, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.burstStart.IsZero() || before.Sub(c.lastMove) > c.policy.Idle {
+		c.burstStart, c.burstBytes = before, 0
+	}
+	c.burstBytes += int64(n)
+	c.lastMove = time.Now()
+	return c.policy.check(c.burstStart, c.burstBytes)
+}
+
+func (c *stallConn) Read(p []byte) (int, error) {
+	if !c.guardReads || c.policy.Idle <= 0 {
+		return c.Conn.Read(p)
+	}
+	now := time.Now()
+	c.Conn.SetReadDeadline(now.Add(c.policy.Idle))
+	n, err := c.Conn.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, &stallError{fmt.Sprintf("transfer stalled: no data for %s", c.policy.Idle)}
+	}
+	if err == nil {
+		err = c.track(now, n)
+	}
+	return n, err
+}
+
+func (c *stallConn) Write(p []byte) (int, error) {
+	if !c.guardWrites || c.policy.Idle <= 0 {
+		return c.Conn.Write(p)
+	}
+	now := time.Now()
+	c.Conn.SetWriteDeadline(now.Add(c.policy.Idle))
+	n, err := c.Conn.Write(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, &stallError{fmt.Sprintf("transfer stalled: receiver took no data for %s", c.policy.Idle)}
+	}
+	if err == nil {
+		err = c.track(now, n)
+	}
+	return n, err
+}
+
+// stallListener hands out connections whose writes are stall-guarded, and
+// reads too when the peer is the one sending (uploads).
+type stallListener struct {
+	net.Listener
+	policy     StallPolicy
+	guardReads bool
+}
+
+func (l *stallListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &stallConn{Conn: c, policy: l.policy, guardReads: l.guardReads, guardWrites: true}, nil
+}
+
+// newStallClient returns an HTTP client without an overall timeout whose
+// connections abort reads (downloads) and writes (uploads) that stop making
+// progress.
+func newStallClient(p StallPolicy, dial DialFunc) *http.Client {
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &stallConn{Conn: c, policy: p, guardReads: true, guardWrites: true}, nil
+	}
+	if p.Idle > 0 {
+		tr.ResponseHeaderTimeout = p.Idle
+	}
+	return &http.Client{Transport: tr}
+}
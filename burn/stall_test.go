@@ -0,0 +1,111 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallConnWriteAbortsWhenReaderStops(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &stallConn{Conn: server, policy: StallPolicy{Idle: 50 * time.Millisecond}, guardWrites: true}
+
+	start := time.Now()
+	_, err := conn.Write([]byte("nobody is reading this"))
+	var se *stallError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected stall error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("stall took too long to detect: %v", elapsed)
+	}
+}
+
+func TestStallConnSlowButSteadyReaderSurvives(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &stallConn{Conn: server, policy: StallPolicy{Idle: 100 * time.Millisecond}, guardWrites: true}
+
+	// The reader trickles one byte at a time, well inside the idle window.
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			time.Sleep(10 * time.Millisecond)
+			if _, err := client.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		if _, err := conn.Write([]byte{'x'}); err != nil {
+			t.Fatalf("write %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestStallConnMinRate(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &stallConn{
+		Conn:        server,
+		policy:      StallPolicy{Idle: 50 * time.Millisecond, MinRate: 1 << 20},
+		guardWrites: true,
+	}
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			time.Sleep(5 * time.Millisecond)
+			if _, err := client.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write([]byte{'x'}); err != nil {
+			if !strings.Contains(err.Error(), "too slow") {
+				t.Fatalf("expected rate error, got %v", err)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected slow reader to be aborted")
+}
+
+func TestReceiveAbortsStalledServer(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"slow.bin\"")
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("12345"))
+		w.(http.Flusher).Flush()
+		<-release // ...and then nothing
+	}))
+	defer server.Close()
+	defer close(release)
+
+	opts := ReceiveOptions{Dir: t.TempDir(), Stall: StallPolicy{Idle: 100 * time.Millisecond}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := Receive(context.Background(), server.URL, opts)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "stalled") {
+			t.Fatalf("expected stall error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("receive did not give up on a stalled server")
+	}
+}
@@ -0,0 +1,91 @@
+package burn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --- STREAMS ---
+// send - serves whatever arrives on stdin (pg_dump | tail-burn send -). The
+// size and digest aren't known until the pipe closes, so the response is
+// chunked and both travel as HTTP trailers after the last byte. A stream can
+// be read exactly once: there is no Range, no resume, and a failed transfer
+// burns the offer.
+
+const (
+	streamHeader  = "X-Tail-Burn-Stream"
+	lengthTrailer = "X-Tail-Burn-Length"
+)
+
+// serveStream copies src to w (encrypted with key, if set) and sends the
+// plaintext length and SHA-256 as trailers.
+func serveStream(w http.ResponseWriter, src io.Reader, fileName string, key []byte, isSmartClient bool) (n int64, digest string, err error) {
+	w.Header().Set(streamHeader, "true")
+	w.Header().Set("Trailer", lengthTrailer+", "+digestHeader)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+
+	var out io.Writer = w
+	var enc *encryptWriter
+	if key != nil {
+		salt, err := randomSalt()
+		if err != nil {
+			return 0, "", err
+		}
+		aead, err := streamAEAD(key, salt)
+		if err != nil {
+			return 0, "", err
+		}
+		enc = newEncryptWriter(w, aead)
+		out = enc
+		w.Header().Set(encSaltHeader, hex.EncodeToString(salt))
+		if !isSmartClient {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.enc\"", fileName))
+		}
+	}
+
+	h := sha256.New()
+	n, err = io.Copy(out, io.TeeReader(src, h))
+	if err == nil && enc != nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		return n, "", err
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+	w.Header().Set(lengthTrailer, strconv.FormatInt(n, 10))
+	w.Header().Set(digestHeader, digest)
+	return n, digest, nil
+}
+
+// isStream reports whether a response is a one-shot stream.
+func isStream(resp *http.Response) bool {
+	return resp.Header.Get(streamHeader) == "true"
+}
+
+// checkTrailers verifies a fully read stream against the length and digest
+// the server sent after it. Trailers are only filled in once the body hit
+// EOF, so callers drain the body first.
+func checkTrailers(resp *http.Response, size int64, sum []byte) error {
+	length := resp.Trailer.Get(lengthTrailer)
+	if length == "" {
+		return fmt.Errorf("stream ended without its length trailer; it may be truncated")
+	}
+	want, err := strconv.ParseInt(length, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad length trailer %q", length)
+	}
+	if size != want {
+		return fmt.Errorf("download incomplete: expected %d bytes, got %d", want, size)
+	}
+	digest := strings.ToLower(resp.Trailer.Get(digestHeader))
+	if digest == "" {
+		return fmt.Errorf("stream ended without its digest trailer")
+	}
+	return checkDigest(digest, sum)
+}
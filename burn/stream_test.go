@@ -0,0 +1,190 @@
+package burn
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns what it wrote.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+	fn()
+	os.Stdout = stdout
+	w.Close()
+	return <-done
+}
+
+func TestStreamRoundTripToFile(t *testing.T) {
+	dump := strings.Repeat("INSERT INTO t VALUES (1);\n", 10000)
+	f := newTestShare(t, withPayload(&payload{stream: strings.NewReader(dump), name: "db.sql", size: -1}))
+	dest := t.TempDir()
+
+	if _, err := Receive(context.Background(), f.server.URL+"/secret", ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "db.sql"))
+	if err != nil || string(got) != dump {
+		t.Fatalf("expected the streamed payload, got %d bytes (%v)", len(got), err)
+	}
+	if _, err := os.Stat(partETagPath(filepath.Join(dest, "db.sql.part"))); !os.IsNotExist(err) {
+		t.Fatalf("expected no ETag sidecar for a stream")
+	}
+	if f.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", f.acks.Load())
+	}
+	if reason := <-f.shutdown; reason != "Client confirmed receipt" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+}
+
+func TestEncryptedStreamToStdout(t *testing.T) {
+	key, _ := newOfferKey()
+	dump := strings.Repeat("x", 3*encChunkSize+17)
+	f := newTestShare(t, withPayload(&payload{stream: strings.NewReader(dump), name: "db.sql", size: -1, key: key}))
+	dest := t.TempDir()
+
+	var err error
+	out := captureStdout(t, func() {
+		_, err = Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{key: key}), ReceiveOptions{Dir: dest, Writer: os.Stdout, Stall: DefaultStallPolicy})
+	})
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if string(out) != dump {
+		t.Fatalf("expected exactly the payload on stdout, got %d bytes", len(out))
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing written to disk, got %v", entries)
+	}
+	if f.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", f.acks.Load())
+	}
+}
+
+func TestFileToStdout(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	digest, _ := fileDigest(filePath)
+	f := newTestShare(t, withPayload(&payload{paths: []string{filePath}, name: "plans.pdf"}))
+
+	var err error
+	out := captureStdout(t, func() {
+		_, err = Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{digest: digest}), ReceiveOptions{Writer: os.Stdout, Stall: DefaultStallPolicy})
+	})
+	if err != nil || string(out) != "top secret plans" {
+		t.Fatalf("receive = %q, %v", out, err)
+	}
+	if f.acks.Load() != 1 {
+		t.Fatalf("expected one ACK, got %d", f.acks.Load())
+	}
+}
+
+func TestReceiveToStdoutRejectsResume(t *testing.T) {
+	_, err := Receive(context.Background(), "http://127.0.0.1:1/secret", ReceiveOptions{Writer: os.Stdout, Resume: true, Stall: DefaultStallPolicy})
+	if err == nil || !strings.Contains(err.Error(), "-resume") {
+		t.Fatalf("expected -resume to be rejected with -o -, got %v", err)
+	}
+}
+
+type failingReader struct{ n int }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errors.New("pg_dump crashed")
+	}
+	n := min(len(p), r.n)
+	r.n -= n
+	return n, nil
+}
+
+func TestInterruptedStreamBurns(t *testing.T) {
+	f := newTestShare(t, withPayload(&payload{stream: &failingReader{n: 1 << 20}, name: "db.sql", size: -1}))
+	dest := t.TempDir()
+
+	if _, err := Receive(context.Background(), f.server.URL+"/secret", ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy}); err == nil {
+		t.Fatalf("expected an interrupted stream to fail")
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected the partial stream to be deleted, got %v", entries)
+	}
+	if f.acks.Load() != 0 {
+		t.Fatalf("expected no ACK, got %d", f.acks.Load())
+	}
+	if reason := <-f.shutdown; reason != "Stream interrupted" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+
+	req, _ := http.NewRequest("GET", f.server.URL+"/secret", nil)
+	req.Header.Set("X-Tail-Burn-Client", "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected 410 for a spent stream, got %d", resp.StatusCode)
+	}
+}
+
+func TestReceiveChecksStreamTrailers(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		length, digest string
+		want           string
+	}{
+		{"missing length", "", strings.Repeat("00", 32), "length trailer"},
+		{"short", "99", strings.Repeat("00", 32), "download incomplete"},
+		{"bad digest", "5", strings.Repeat("00", 32), "integrity check failed"},
+		{"missing digest", "5", "", "digest trailer"},
+	} {
+		var acks atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				acks.Add(1)
+				return
+			}
+			w.Header().Set(streamHeader, "true")
+			w.Header().Set("Trailer", lengthTrailer+", "+digestHeader)
+			w.Header().Set("Content-Disposition", `attachment; filename="db.sql"`)
+			io.WriteString(w, "hello")
+			w.(http.Flusher).Flush()
+			if tt.length != "" {
+				w.Header().Set(lengthTrailer, tt.length)
+			}
+			if tt.digest != "" {
+				w.Header().Set(digestHeader, tt.digest)
+			}
+		}))
+		dest := t.TempDir()
+		_, err := Receive(context.Background(), server.URL+"/secret", ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("%s: expected the download to be deleted, got %v", tt.name, entries)
+		}
+		if acks.Load() != 0 {
+			t.Errorf("%s: expected no ACK", tt.name)
+		}
+	}
+}
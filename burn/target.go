@@ -0,0 +1,229 @@
+package burn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"tailscale.com/client/tailscale/apitype"
+)
+
+// --- TARGET EXPRESSIONS ---
+// -target decides who may download. It is a comma-separated list of terms,
+// any one of which is enough:
+//
+//	alice@github              a Tailscale login name
+//	alice@                    that user at any identity provider
+//	tag:ci                    a node carrying that ACL tag
+//	node:build-01             a node by MagicDNS name (short or full)
+//	id:nTQrBL8CNTRL           a node by stable node ID
+//	any-of(t1, t2, ...)       at least one of the terms
+//	all-of(t1, t2, ...)       every term, e.g. all-of(alice@github, tag:laptop)
+//
+// Group membership (group:sre) is resolved by the coordination server and is
+// never revealed to peers, so it can't be checked here; grant the group the
+// tail-burn capability in the policy file and use -acl instead.
+
+// Authorizer decides whether a peer may download an offer. ParseTarget
+// builds one from a -target expression.
+type Authorizer interface {
+	Allow(who *apitype.WhoIsResponse) bool
+	String() string
+}
+
+type loginTarget string
+
+func (t loginTarget) Allow(who *apitype.WhoIsResponse) bool {
+	if who.UserProfile == nil {
+		return false
+	}
+	login := who.UserProfile.LoginName
+	if strings.HasSuffix(string(t), "@") {
+		return len(login) > len(t) && strings.EqualFold(login[:len(t)], string(t))
+	}
+	return strings.EqualFold(login, string(t))
+}
+
+func (t loginTarget) String() string { return string(t) }
+
+type tagTarget string
+
+func (t tagTarget) Allow(who *apitype.WhoIsResponse) bool {
+	return who.Node != nil && slices.Contains(who.Node.Tags, string(t))
+}
+
+func (t tagTarget) String() string { return string(t) }
+
+type nodeTarget string
+
+func (t nodeTarget) Allow(who *apitype.WhoIsResponse) bool {
+	if who.Node == nil {
+		return false
+	}
+	fqdn := strings.TrimSuffix(who.Node.Name, ".")
+	short, _, _ := strings.Cut(fqdn, ".")
+	return strings.EqualFold(fqdn, string(t)) || strings.EqualFold(short, string(t))
+}
+
+func (t nodeTarget) String() string { return "node:" + string(t) }
+
+type nodeIDTarget string
+
+func (t nodeIDTarget) Allow(who *apitype.WhoIsResponse) bool {
+	return who.Node != nil && string(who.Node.StableID) == string(t)
+}
+
+func (t nodeIDTarget) String() string { return "id:" + string(t) }
+
+type anyOf []Authorizer
+
+func (t anyOf) Allow(who *apitype.WhoIsResponse) bool {
+	for _, a := range t {
+		if a.Allow(who) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t anyOf) String() string { return "any-of(" + joinTargets(t) + ")" }
+
+type allOf []Authorizer
+
+func (t allOf) Allow(who *apitype.WhoIsResponse) bool {
+	for _, a := range t {
+		if !a.Allow(who) {
+			return false
+		}
+	}
+	return len(t) > 0
+}
+
+func (t allOf) String() string { return "all-of(" + joinTargets(t) + ")" }
+
+func joinTargets(terms []Authorizer) string {
+	s := make([]string, len(terms))
+	for i, a := range terms {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// ParseTarget parses a -target expression.
+func ParseTarget(expr string) (Authorizer, error) {
+	terms, err := ParseTargets(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return anyOf(terms), nil
+}
+
+// ParseTargets parses a -target expression into its top-level terms, one
+// per recipient of a per-recipient offer.
+func ParseTargets(expr string) ([]Authorizer, error) {
+	parts, err := splitTopLevel(expr)
+	if err != nil {
+		return nil, err
+	}
+	var terms []Authorizer
+	for _, p := range parts {
+		term, err := parseTargetTerm(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+func parseTargetTerm(term string) (Authorizer, error) {
+	for _, op := range []string{"any-of", "all-of"} {
+		inner, ok := strings.CutPrefix(term, op+"(")
+		if !ok {
+			continue
+		}
+		inner, ok = strings.CutSuffix(inner, ")")
+		if !ok {
+			return nil, fmt.Errorf("target %q: missing closing parenthesis", term)
+		}
+		terms, err := ParseTargets(inner)
+		if err != nil {
+			return nil, err
+		}
+		if op == "all-of" {
+			return allOf(terms), nil
+		}
+		return anyOf(terms), nil
+	}
+
+	switch {
+	case term == "":
+		return nil, fmt.Errorf("empty target term")
+	case strings.ContainsAny(term, "()"):
+		return nil, fmt.Errorf("target %q: unknown operator (expected any-of or all-of)", term)
+	case strings.HasPrefix(term, "tag:") && len(term) > len("tag:"):
+		return tagTarget(term), nil
+	case strings.HasPrefix(term, "node:") && len(term) > len("node:"):
+		return nodeTarget(strings.TrimPrefix(term, "node:")), nil
+	case strings.HasPrefix(term, "id:") && len(term) > len("id:"):
+		return nodeIDTarget(strings.TrimPrefix(term, "id:")), nil
+	case strings.HasPrefix(term, "group:"):
+		return nil, fmt.Errorf("target %q: Tailscale does not reveal group membership to peers; grant the group %s and use -acl", term, Capability)
+	case strings.Contains(term, "@"):
+		return loginTarget(term), nil
+	}
+	return nil, fmt.Errorf("target %q: expected a login name, tag:, node: or id:", term)
+}
+
+// splitTopLevel splits on commas outside parentheses.
+func splitTopLevel(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("target %q: unbalanced parentheses", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("target %q: unbalanced parentheses", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+// peerName is how a peer shows up in logs. Tagged nodes have no meaningful
+// login, so they go by node name.
+func peerName(who *apitype.WhoIsResponse) string {
+	if who.Node != nil && len(who.Node.Tags) > 0 {
+		return strings.TrimSuffix(who.Node.Name, ".")
+	}
+	if who.UserProfile != nil {
+		return who.UserProfile.LoginName
+	}
+	return "unknown"
+}
+
+// peerID identifies a peer for the ACK ledger. All tagged nodes share one
+// login, so the node's stable ID is what counts when there is one.
+func peerID(who *apitype.WhoIsResponse) string {
+	if who.Node != nil && who.Node.StableID != "" {
+		return string(who.Node.StableID)
+	}
+	if who.UserProfile != nil {
+		return who.UserProfile.LoginName
+	}
+	return ""
+}
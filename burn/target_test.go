@@ -0,0 +1,129 @@
+package burn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tail-burn/burn/burntest"
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+func userPeer(login string) *apitype.WhoIsResponse {
+	return &apitype.WhoIsResponse{
+		Node:        &tailcfg.Node{Name: "laptop.tailnet.ts.net.", StableID: "nLAPTOP"},
+		UserProfile: &tailcfg.UserProfile{LoginName: login},
+	}
+}
+
+func taggedPeer(name, id string, tags ...string) *apitype.WhoIsResponse {
+	return &apitype.WhoIsResponse{
+		Node:        &tailcfg.Node{Name: name + ".tailnet.ts.net.", StableID: tailcfg.StableNodeID(id), Tags: tags},
+		UserProfile: &tailcfg.UserProfile{LoginName: "tagged-devices"},
+	}
+}
+
+func TestParseTargetAllow(t *testing.T) {
+	alice := userPeer("alice@example.com")
+	bob := userPeer("Bob@example.com")
+	ci := taggedPeer("build-01", "nBUILD01", "tag:ci")
+	other := taggedPeer("db-01", "nDB01", "tag:prod")
+
+	tests := []struct {
+		expr  string
+		allow []*apitype.WhoIsResponse
+		deny  []*apitype.WhoIsResponse
+	}{
+		{"alice@example.com", []*apitype.WhoIsResponse{alice}, []*apitype.WhoIsResponse{bob, ci}},
+		{"alice@example.com, bob@example.com", []*apitype.WhoIsResponse{alice, bob}, []*apitype.WhoIsResponse{ci}},
+		{"alice@", []*apitype.WhoIsResponse{alice, userPeer("alice@github")}, []*apitype.WhoIsResponse{bob, userPeer("alice"), userPeer("malice@example.com")}},
+		{"tag:ci", []*apitype.WhoIsResponse{ci}, []*apitype.WhoIsResponse{alice, other}},
+		{"node:build-01", []*apitype.WhoIsResponse{ci}, []*apitype.WhoIsResponse{other}},
+		{"node:build-01.tailnet.ts.net", []*apitype.WhoIsResponse{ci}, []*apitype.WhoIsResponse{other}},
+		{"id:nDB01", []*apitype.WhoIsResponse{other}, []*apitype.WhoIsResponse{ci}},
+		{"any-of(tag:ci, id:nDB01)", []*apitype.WhoIsResponse{ci, other}, []*apitype.WhoIsResponse{alice}},
+		{"all-of(alice@example.com, id:nLAPTOP)", []*apitype.WhoIsResponse{alice}, []*apitype.WhoIsResponse{bob}},
+		{"all-of(tag:ci, node:db-01)", nil, []*apitype.WhoIsResponse{ci, other}},
+		{"bob@example.com, all-of(tag:ci, any-of(node:build-01, node:build-02))", []*apitype.WhoIsResponse{bob, ci}, []*apitype.WhoIsResponse{alice, other}},
+	}
+	for _, tt := range tests {
+		auth, err := ParseTarget(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseTarget(%q): %v", tt.expr, err)
+		}
+		for _, who := range tt.allow {
+			if !auth.Allow(who) {
+				t.Errorf("%q should allow %s", tt.expr, peerName(who))
+			}
+		}
+		for _, who := range tt.deny {
+			if auth.Allow(who) {
+				t.Errorf("%q should deny %s", tt.expr, peerName(who))
+			}
+		}
+	}
+}
+
+func TestParseTargetErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"alice@example.com,",
+		"tag:",
+		"bob",
+		"group:sre",
+		"all-of(tag:ci",
+		"none-of(tag:ci)",
+		"tag:ci)",
+	} {
+		if _, err := ParseTarget(expr); err == nil {
+			t.Errorf("ParseTarget(%q): expected error", expr)
+		}
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	auth, err := ParseTarget("alice@example.com,all-of(tag:ci,node:build-01)")
+	if err != nil {
+		t.Fatalf("parseTarget: %v", err)
+	}
+	want := "any-of(alice@example.com, all-of(tag:ci, node:build-01))"
+	if got := auth.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterHandlersTaggedTarget(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "artifact.bin")
+	os.WriteFile(filePath, []byte("build output"), 0600)
+	auth, _ := ParseTarget("tag:ci")
+
+	for _, tt := range []struct {
+		who  *apitype.WhoIsResponse
+		want int
+	}{
+		{taggedPeer("build-01", "nBUILD01", "tag:ci"), http.StatusOK},
+		{taggedPeer("db-01", "nDB01", "tag:prod"), http.StatusForbidden},
+		{userPeer("alice@example.com"), http.StatusForbidden},
+	} {
+		mux := http.NewServeMux()
+		serveShare(mux, burntest.NewPeerClient(tt.who),
+			&payload{paths: []string{filePath}, name: "artifact.bin", size: 12},
+			&recipient{target: auth, secretPath: "/secret", done: make(chan string, 1)})
+		server := httptest.NewServer(mux)
+
+		req, _ := http.NewRequest("GET", server.URL+"/secret", nil)
+		req.Header.Set("X-Tail-Burn-Client", "true")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		resp.Body.Close()
+		server.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", peerName(tt.who), tt.want, resp.StatusCode)
+		}
+	}
+}
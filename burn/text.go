@@ -0,0 +1,168 @@
+package burn
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// --- ONE-TIME SECRETS ---
+// send -text serves a short secret (password, token, snippet) instead of a
+// file. It is read from stdin or a hidden prompt, never from argv, shown once
+// in the browser or printed by receive, and zeroed in memory once burned.
+
+const textHeader = "X-Tail-Burn-Text"
+
+// MaxSecretSize is the largest secret an offer carries or Receive accepts.
+const MaxSecretSize = 1 << 20
+
+// secretMarker stands in for the secret when the reveal page is rendered, so
+// the secret itself is escaped straight into the response and never copied
+// into an immutable string.
+const secretMarker = "TAIL-BURN-SECRET-MARKER"
+
+// noCache keeps the secret out of browser and proxy caches.
+func noCache(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+}
+
+// secretData fills secretHTMLTemplate.
+type secretData struct {
+	Sender   string
+	Size     string
+	Revealed bool
+	Secret   string
+}
+
+// writeRevealedSecret renders the reveal page with secret HTML-escaped in
+// place of the marker.
+func writeRevealedSecret(w io.Writer, secret []byte) error {
+	var page bytes.Buffer
+	if err := secretTemplate.Execute(&page, secretData{Revealed: true, Secret: secretMarker}); err != nil {
+		return err
+	}
+	head, tail, ok := bytes.Cut(page.Bytes(), []byte(secretMarker))
+	if !ok {
+		return fmt.Errorf("secret template has no placeholder")
+	}
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+	template.HTMLEscape(w, secret)
+	_, err := w.Write(tail)
+	return err
+}
+
+// registerTextHandlers serves one share of a secret. The returned func
+// zeroes it.
+func (s *Server) registerTextHandlers(mux *http.ServeMux, p *payload, share *recipient) func() {
+	var used atomic.Bool
+	var acks ackLedger
+	var mu sync.Mutex // guards the secret against being zeroed mid-write
+	secret := p.secret
+
+	zeroize := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		clear(secret)
+	}
+
+	mux.HandleFunc(share.ackPath(), s.ackHandler(share, &acks, &used, zeroize))
+
+	mux.HandleFunc(share.secretPath, func(w http.ResponseWriter, r *http.Request) {
+		noCache(w)
+		who, err := s.Client.WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Identity Error", 500)
+			return
+		}
+		if !share.target.Allow(who) {
+			s.blocked(share.hosted, who)
+			http.Error(w, "Forbidden", 403)
+			return
+		}
+
+		isSmartClient := r.Header.Get("X-Tail-Burn-Client") == "true"
+		if used.Load() {
+			if !isSmartClient {
+				w.WriteHeader(http.StatusGone)
+				_ = burnedTemplate.Execute(w, nil)
+				return
+			}
+			http.Error(w, "Gone", http.StatusGone)
+			return
+		}
+
+		switch {
+		case isSmartClient && (r.Method == "GET" || r.Method == "HEAD"):
+			// Smart client: raw bytes now, burn on its ACK
+			w.Header().Set(textHeader, "true")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Length", strconv.Itoa(len(secret)))
+			if r.Method == "HEAD" {
+				return
+			}
+			token, err := acks.issue(peerID(who))
+			if err != nil {
+				http.Error(w, "Token Error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(tokenHeader, token)
+			s.logf("🚀 Sending secret to %s...", peerName(who))
+			s.transferStarted(share.hosted, who)
+			mu.Lock()
+			_, err = w.Write(secret)
+			mu.Unlock()
+			if err != nil {
+				s.logf("❌ Transfer failed: %v", err)
+				return
+			}
+			acks.finish(token, p.linkDigest)
+
+		case r.Method == "GET":
+			// Browser: ask before revealing, so link previews don't burn it
+			sender := "A Tailscale User"
+			st, err := s.Client.Status(r.Context())
+			if err == nil && st != nil && st.Self != nil {
+				if profile, ok := st.User[st.Self.UserID]; ok {
+					sender = profile.LoginName
+				}
+			}
+			if err := secretTemplate.Execute(w, secretData{Sender: sender, Size: FormatBytes(int64(len(secret)))}); err != nil {
+				http.Error(w, "Template Error", http.StatusInternalServerError)
+			}
+
+		case r.Method == "POST":
+			if !used.CompareAndSwap(false, true) {
+				w.WriteHeader(http.StatusGone)
+				_ = burnedTemplate.Execute(w, nil)
+				return
+			}
+			s.logf("🚀 Revealing secret to %s...", peerName(who))
+			s.transferStarted(share.hosted, who)
+			mu.Lock()
+			err := writeRevealedSecret(w, secret)
+			mu.Unlock()
+			zeroize()
+			if err != nil {
+				s.logf("❌ Reveal failed: %v", err)
+			}
+			select {
+			case share.done <- ReasonReveal:
+			default:
+			}
+
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	return zeroize
+}
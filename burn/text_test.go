@@ -0,0 +1,87 @@
+package burn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSecretRevealOnceInBrowser(t *testing.T) {
+	secret := []byte("hunter2 <script>alert(1)</script>")
+	f := newTestShare(t, withPayload(&payload{secret: secret}))
+
+	resp, err := http.Get(f.server.URL + "/secret")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(page), "hunter2") {
+		t.Fatalf("secret must not be on the page before it is revealed")
+	}
+	if !strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		t.Fatalf("expected no-store, got %q", resp.Header.Get("Cache-Control"))
+	}
+
+	resp, err = http.Post(f.server.URL+"/secret", "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	page, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), "hunter2 &lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Fatalf("expected the escaped secret on the reveal page, got:\n%s", page)
+	}
+	if strings.Contains(string(page), secretMarker) {
+		t.Fatalf("marker leaked into the page")
+	}
+	if !strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		t.Fatalf("expected no-store on the reveal page")
+	}
+	if reason := <-f.shutdown; reason != "Secret revealed" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+	if !bytes.Equal(secret, make([]byte, len(secret))) {
+		t.Fatalf("expected the secret to be zeroed after the reveal")
+	}
+
+	resp, err = http.Post(f.server.URL+"/secret", "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected 410 on a second reveal, got %d", resp.StatusCode)
+	}
+}
+
+func TestReceiveReturnsSecretWithoutWritingIt(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	sum := sha256.Sum256(secret)
+	digest := hex.EncodeToString(sum[:])
+	f := newTestShare(t, withPayload(&payload{secret: secret, linkDigest: digest}))
+	dest := t.TempDir()
+
+	res, err := Receive(context.Background(), buildLink(f.server.URL+"/secret", linkSecrets{digest: digest}), ReceiveOptions{Dir: dest, Stall: DefaultStallPolicy})
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if string(res.Secret) != "correct horse battery staple" || len(res.Paths) != 0 {
+		t.Fatalf("expected the secret in the result and no paths, got %+v", res)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("expected nothing written to disk, got %v", entries)
+	}
+	if reason := <-f.shutdown; reason != "Client confirmed receipt" {
+		t.Fatalf("unexpected shutdown reason %q", reason)
+	}
+	if !bytes.Equal(secret, make([]byte, len(secret))) {
+		t.Fatalf("expected the secret to be zeroed after the ACK")
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tail-burn/burn"
+)
+
+// --- DAEMON ---
+// tail-burn daemon keeps one ephemeral node up and hosts the offers send
+// -via-daemon hands it over its control socket (see burn.Daemon); list,
+// revoke and status talk to it the same way. It tears itself down once it
+// has hosted nothing for -idle.
+
+// controlTimeout bounds a control request; adding an offer hashes it first.
+const controlTimeout = 10 * time.Minute
+
+func runDaemon() int {
+	daemonCmd := flag.NewFlagSet("daemon", flag.ExitOnError)
+	socket := daemonCmd.String("socket", burn.DefaultControlSocket(), "Control socket to listen on")
+	idle := daemonCmd.Duration("idle", 30*time.Minute, "Shut down after hosting no offers for this long (0 = never)")
+	authKey := daemonCmd.String("authkey", "", "Auth key for the daemon's node (default: TS_AUTHKEY)")
+	stallTimeout := daemonCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort a transfer when the receiver takes no data for this long (0 = never)")
+	minRate := daemonCmd.Int64("min-rate", 0, "Abort a transfer slower than this many bytes/s (0 = off)")
+	debugMode := daemonCmd.Bool("debug", false, "Enable verbose Tailscale logs")
+	auditPath := daemonCmd.String("audit", "", "Append a hash-chained JSONL record of every access to this file")
+	daemonCmd.Parse(os.Args[2:])
+
+	key := *authKey
+	if key == "" {
+		key = os.Getenv("TS_AUTHKEY")
+	}
+	audit := openAudit(*auditPath)
+	defer audit.Close()
+	// Claim the socket first: a second daemon must not join the tailnet
+	ctl, err := burn.ListenControl(*socket)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer ctl.Close() // removes the socket
+
+	s, cleanupNode, err := burn.NewEphemeralNode("tail-burn-daemon", key, *debugMode)
+	if err != nil {
+		log.Fatalf("❌ Error creating node: %v", err)
+	}
+	defer cleanupNode()
+
+	shutdownSignal := make(chan string, 1)
+	interrupted, stopSignals := interruptible(shutdownSignal)
+	defer stopSignals()
+
+	localClient, err := s.LocalClient()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return exitError
+	}
+	srv := newSendServer(localClient, burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate}, os.Stdout)
+	srv.Audit = audit
+
+	fmt.Println("🔌 Joining tailnet...")
+	ctx, cancel := context.WithTimeout(interrupted, 2*time.Minute)
+	ln, err := srv.ListenTailnet(ctx, s)
+	cancel()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return exitError
+	}
+	d := &burn.Daemon{Server: srv, Node: s.Hostname, IdleTimeout: *idle}
+
+	fmt.Println("🔥 \033[1mtail-burn\033[0m (Daemon Mode)")
+	fmt.Println("-------------------------------------------")
+	fmt.Printf("🌐 Node:    %s\n", srv.BaseURL)
+	fmt.Printf("🔌 Control: %s\n", *socket)
+	if *idle > 0 {
+		fmt.Printf("💤 Idle:    shuts down after %s without offers\n", *idle)
+	}
+	if *auditPath != "" {
+		fmt.Printf("📜 Audit:   %s\n", *auditPath)
+	}
+	fmt.Println("-------------------------------------------")
+	fmt.Println("💻 Send with: \033[33mtail-burn send -via-daemon -target=<user> <path>\033[0m")
+
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			log.Printf("❌ Server error: %v", err)
+		}
+	}()
+	go func() {
+		if err := d.ServeControl(ctl); err != nil {
+			log.Printf("❌ Control socket error: %v", err)
+		}
+	}()
+
+	idleCtx, stopIdle := context.WithCancel(context.Background())
+	defer stopIdle()
+	idled := make(chan struct{})
+	go func() {
+		d.Idle(idleCtx)
+		close(idled)
+	}()
+
+	code := exitOK
+	select {
+	case reason := <-shutdownSignal:
+		fmt.Printf("\n🛑 Shutting down: %s\n", reason)
+		code = exitCode(reason)
+		d.BurnAll(reason)
+	case <-idled:
+		fmt.Printf("\n💤 No offers for %s, shutting down\n", *idle)
+		d.BurnAll(burn.ReasonTimeout) // one may have come in just now
+	}
+	ctl.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+	return code
+}
+
+// sendViaDaemon hands spec to the daemon on socket, resolving relative
+// paths and times here, and prints its links.
+func sendViaDaemon(socket string, spec burn.OfferSpec, notBefore, expires string) int {
+	ui := human()
+	for i, p := range spec.Paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		spec.Paths[i] = abs
+	}
+	var err error
+	if spec.NotBefore, spec.Expires, err = burn.ParseSchedule(notBefore, expires, time.Now()); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(spec.Paths) > 0 {
+		fmt.Fprintln(ui, "🔒 Handing the offer to the daemon (it hashes the payload)...")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	st, err := burn.NewControlClient(socket).Add(ctx, spec)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		events.emit(event{Event: "error", Error: err.Error()})
+		return errExitCode(err)
+	}
+
+	fmt.Fprintf(ui, "📨 Offer %s hosted by the daemon\n", st.ID)
+	fmt.Fprintf(ui, "📦 %s (%s)\n", st.Name, burn.FormatBytes(st.Size))
+	fmt.Fprintf(ui, "🔒 SHA-256: %s\n", st.Digest)
+	fmt.Fprintf(ui, "⏳ Burns: %s (in %s)\n", st.Expires.Format("2006-01-02 15:04:05 MST"), burn.FormatRemaining(time.Until(st.Expires)))
+	for _, l := range st.Links {
+		printLink(ui, l, len(st.Links) > 1)
+		ready := event{
+			Event:      "link_ready",
+			ID:         st.ID,
+			URL:        l.URL,
+			BrowserURL: l.BrowserURL,
+			NotBefore:  st.NotBefore,
+			Expires:    st.Expires,
+			Name:       st.Name,
+			Size:       st.Size,
+			Digest:     st.Digest,
+		}
+		if spec.PerRecipient {
+			ready.Recipient = l.Recipient
+		}
+		events.emit(ready)
+	}
+	fmt.Fprintf(ui, "🗑️  Revoke with: \033[33mtail-burn revoke %s\033[0m\n", st.ID)
+	return exitOK
+}
+
+// printLink shows where one recipient collects an offer.
+func printLink(w io.Writer, l burn.Link, withRecipient bool) {
+	if withRecipient {
+		fmt.Fprintf(w, "👤 %s\n", l.Recipient)
+	}
+	fmt.Fprintf(w, "🌐 Browser Link: \033[32m%s\033[0m\n", l.BrowserURL)
+	fmt.Fprintf(w, "💻 Command:      \033[33mtail-burn receive '%s'\033[0m\n", l.URL)
+}
+
+// controlFlags parses the flags list, revoke and status share.
+func controlFlags(name string) (*flag.FlagSet, *burn.ControlClient, bool) {
+	cmd := flag.NewFlagSet(name, flag.ExitOnError)
+	socket := cmd.String("socket", burn.DefaultControlSocket(), "The daemon's control socket")
+	output := cmd.String("output", "text", "text, or json")
+	cmd.Parse(os.Args[2:])
+	if *output != "text" && *output != "json" {
+		log.Fatalf("❌ -output must be text or json, not %q", *output)
+	}
+	return cmd, burn.NewControlClient(*socket), *output == "json"
+}
+
+func writeJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func runList() int {
+	_, client, asJSON := controlFlags("list")
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	list, err := client.List(ctx)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return errExitCode(err)
+	}
+	if asJSON {
+		writeJSON(list)
+		return exitOK
+	}
+	printOfferList(os.Stdout, list, time.Now())
+	return exitOK
+}
+
+// printOfferList shows the daemon's offers as of now.
+func printOfferList(w io.Writer, list []burn.OfferStatus, now time.Time) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "📭 The daemon hosts no offers.")
+		return
+	}
+	for _, st := range list {
+		size := burn.FormatBytes(st.Size)
+		if st.Size < 0 {
+			size = "unknown size"
+		}
+		var recipients []string
+		for _, l := range st.Links {
+			recipients = append(recipients, l.Recipient)
+		}
+		fmt.Fprintf(w, "🔥 %s  %s (%s) for %s, burns in %s\n", st.ID, st.Name, size, strings.Join(recipients, ", "), burn.FormatRemaining(st.Expires.Sub(now)))
+		if now.Before(st.NotBefore) {
+			fmt.Fprintf(w, "   🔓 Opens in %s\n", burn.FormatRemaining(st.NotBefore.Sub(now)))
+		}
+		if n := len(st.Collected) + len(st.Pending); n > 0 {
+			fmt.Fprintf(w, "   📋 Collected %d/%d\n", len(st.Collected), n)
+		}
+		for _, l := range st.Links {
+			if len(st.Links) > 1 {
+				fmt.Fprintf(w, "   👤 %s: %s\n", l.Recipient, l.URL)
+			} else {
+				fmt.Fprintf(w, "   💻 %s\n", l.URL)
+			}
+		}
+	}
+}
+
+func runRevoke() int {
+	cmd, client, _ := controlFlags("revoke")
+	if cmd.NArg() == 0 {
+		fmt.Println("Usage: tail-burn revoke [-socket=<path>] <id>...")
+		os.Exit(1)
+	}
+	code := exitOK
+	for _, id := range cmd.Args() {
+		ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+		err := client.Revoke(ctx, id)
+		cancel()
+		if err != nil {
+			log.Printf("❌ %v", err)
+			code = errExitCode(err)
+			continue
+		}
+		fmt.Printf("🔥 Revoked %s\n", id)
+	}
+	return code
+}
+
+func runStatus() int {
+	_, client, asJSON := controlFlags("status")
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	st, err := client.Status(ctx)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return errExitCode(err)
+	}
+	if asJSON {
+		writeJSON(st)
+		return exitOK
+	}
+	fmt.Printf("🟢 Daemon running (pid %d) since %s\n", st.PID, st.Started.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("🌐 Node:   %s (%s)\n", st.Node, st.URL)
+	fmt.Printf("📦 Offers: %d\n", st.Offers)
+	switch {
+	case st.IdleTimeout <= 0:
+		fmt.Println("💤 Idle:   never shuts down")
+	case st.Offers == 0:
+		fmt.Printf("💤 Idle:   shuts down in %s\n", burn.FormatRemaining(time.Until(st.IdleSince.Add(st.IdleTimeout))))
+	default:
+		fmt.Printf("💤 Idle:   shuts down %s after the last offer burns\n", st.IdleTimeout)
+	}
+	return exitOK
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"tail-burn/burn"
+)
+
+func TestPrintOfferList(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	printOfferList(&out, nil, now)
+	if !strings.Contains(out.String(), "no offers") {
+		t.Fatalf("expected an empty list to say so, got %q", out.String())
+	}
+
+	out.Reset()
+	printOfferList(&out, []burn.OfferStatus{
+		{
+			ID: "a1b2c3d4", Name: "plans.pdf", Size: 2048, Expires: now.Add(9 * time.Minute),
+			Links: []burn.Link{{Recipient: "alice@example.com", URL: "https://node/abc#sha256=ff"}},
+		},
+		{
+			ID: "e5f6a7b8", Name: "release.tar", Size: 1 << 20, Expires: now.Add(time.Hour), NotBefore: now.Add(time.Minute),
+			Links: []burn.Link{
+				{Recipient: "alice@example.com", URL: "https://node/def"},
+				{Recipient: "bob@example.com", URL: "https://node/ghi"},
+			},
+			Collected: []string{"alice@example.com"},
+			Pending:   []string{"bob@example.com"},
+		},
+	}, now)
+	for _, want := range []string{
+		"a1b2c3d4  plans.pdf (2.0 KB) for alice@example.com",
+		"💻 https://node/abc#sha256=ff",
+		"e5f6a7b8  release.tar",
+		"for alice@example.com, bob@example.com",
+		"🔓 Opens in",
+		"Collected 1/2",
+		"👤 bob@example.com: https://node/ghi",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"tail-burn/burn"
+)
+
+// --- EVENTS & EXIT CODES ---
+// With -output=json, send and receive write one JSON event per line to
+// stdout for scripts; the screen UI moves to stderr. Either way, both exit
+// with a code that says how the offer ended (see exitCode and errExitCode).
+
+// pushReason burns an offer whose push the listener refused (the library's
+// reasons are burn.Reason*; interruptReason is in gc.go).
+const pushReason = "Push refused"
+
+// Exit codes. 2 is left to the flag package (bad flags).
+const (
+	exitOK          = 0   // delivered: the offer burned after an ACK, reveal or upload
+	exitError       = 1   // anything else that failed
+	exitTimeout     = 3   // the offer expired before it was collected
+	exitAuth        = 4   // refused: wrong identity, wrong code, -from mismatch, push refused
+	exitGone        = 5   // receive: the link is already burned
+	exitInterrupted = 130 // SIGINT/SIGTERM
+)
+
+// reasonExitCodes maps each shutdown reason to send's exit code.
+var reasonExitCodes = map[string]int{
+	burn.ReasonAck:          exitOK,
+	burn.ReasonReveal:       exitOK,
+	burn.ReasonBrowser:      exitOK,
+	burn.ReasonUpload:       exitOK,
+	burn.ReasonCollected:    exitOK,
+	burn.ReasonTimeout:      exitTimeout,
+	burn.ReasonCodeAttempts: exitAuth,
+	burn.ReasonStream:       exitError,
+	pushReason:              exitAuth,
+	interruptReason:         exitInterrupted,
+}
+
+// exitCode is send's exit code for the reason its offer burned.
+func exitCode(reason string) int {
+	if code, ok := reasonExitCodes[reason]; ok {
+		return code
+	}
+	return exitError
+}
+
+// errExitCode is receive's exit code for err.
+func errExitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, burn.ErrForbidden):
+		return exitAuth
+	case errors.Is(err, burn.ErrGone):
+		return exitGone
+	}
+	return exitError
+}
+
+// event is one line of -output=json. Fields that don't apply are left out.
+type event struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	ID         string    `json:"id,omitempty"` // a daemon's offer
+	URL        string    `json:"url,omitempty"`
+	BrowserURL string    `json:"browserUrl,omitempty"`
+	Code       string    `json:"code,omitempty"`
+	NotBefore  time.Time `json:"notBefore,omitzero"`
+	Expires    time.Time `json:"expires,omitzero"`
+	Recipient  string    `json:"recipient,omitempty"` // a -per-recipient share
+	Identity   string    `json:"identity,omitempty"`  // the peer's login (or tagged node)
+	Node       string    `json:"node,omitempty"`      // the peer's node
+	Name       string    `json:"name,omitempty"`
+	Size       int64     `json:"size,omitempty"` // -1 for a stream
+	Bytes      int64     `json:"bytes,omitempty"`
+	Total      int64     `json:"total,omitempty"` // -1 if unknown
+	Digest     string    `json:"sha256,omitempty"`
+	Paths      []string  `json:"paths,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// eventLog writes events as NDJSON. A nil eventLog discards them.
+type eventLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// events is where this process reports, set by -output=json.
+var events *eventLog
+
+func newEventLog(w io.Writer) *eventLog {
+	return &eventLog{enc: json.NewEncoder(w)}
+}
+
+// setOutput applies an -output flag.
+func setOutput(format string) error {
+	switch format {
+	case "text":
+		return nil
+	case "json":
+		events = newEventLog(os.Stdout)
+		return nil
+	}
+	return fmt.Errorf("-output must be text or json, not %q", format)
+}
+
+func (l *eventLog) emit(e event) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(e); err != nil {
+		log.Printf("❌ Cannot write event: %v", err)
+	}
+}
+
+// human is where the screen UI goes: stderr once stdout carries events.
+func human() io.Writer {
+	if events != nil {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// peerEvent describes p for an event.
+func peerEvent(name string, p burn.Peer) event {
+	return event{Event: name, Identity: p.Name, Node: p.Node}
+}
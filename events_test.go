@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tail-burn/burn"
+	"tail-burn/burn/burntest"
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+// captureEvents turns on the event stream for one test.
+func captureEvents(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	events = newEventLog(&buf)
+	t.Cleanup(func() { events = nil })
+	return &buf
+}
+
+// decodeEvents parses NDJSON events by name.
+func decodeEvents(t *testing.T, buf *bytes.Buffer) map[string][]event {
+	t.Helper()
+	got := make(map[string][]event)
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var e event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("bad event line: %v", err)
+		}
+		if e.Time.IsZero() {
+			t.Fatalf("event %q has no time", e.Event)
+		}
+		got[e.Event] = append(got[e.Event], e)
+	}
+	return got
+}
+
+// hostOffer serves a file for target@example.com the way send does, with
+// peers identified as login.
+func hostOffer(t *testing.T, login string) *burn.Hosted {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "plans.pdf")
+	os.WriteFile(filePath, []byte("top secret plans"), 0600)
+	target, _ := burn.ParseTarget("target@example.com")
+
+	client := burntest.NewPeerClient(&apitype.WhoIsResponse{
+		UserProfile: &tailcfg.UserProfile{LoginName: login},
+		Node:        &tailcfg.Node{Name: "laptop.tailnet.ts.net."},
+	})
+	srv := newSendServer(client, burn.DefaultStallPolicy, io.Discard)
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	srv.BaseURL = server.URL
+	h, err := srv.Add(&burn.Offer{Paths: []string{filePath}, Target: target, Expires: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	t.Cleanup(func() { h.Burn("test over") })
+	return h
+}
+
+func TestExitCodes(t *testing.T) {
+	for reason, want := range map[string]int{
+		burn.ReasonAck:          exitOK,
+		burn.ReasonCollected:    exitOK,
+		burn.ReasonTimeout:      exitTimeout,
+		burn.ReasonCodeAttempts: exitAuth,
+		interruptReason:         exitInterrupted,
+		"something new":         exitError,
+	} {
+		if got := exitCode(reason); got != want {
+			t.Errorf("exitCode(%q) = %d, want %d", reason, got, want)
+		}
+	}
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{fmt.Errorf("connection failed: %w", burn.ErrForbidden), exitAuth},
+		{fmt.Errorf("%w: too many code attempts", burn.ErrGone), exitGone},
+		{errors.New("download interrupted"), exitError},
+	} {
+		if got := errExitCode(tt.err); got != tt.want {
+			t.Errorf("errExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReceiveEmitsEvents(t *testing.T) {
+	buf := captureEvents(t)
+	h := hostOffer(t, "target@example.com")
+
+	if err := receive(context.Background(), h.Links()[0].URL, receiveOptions(t.TempDir(), "")); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	<-h.Done()
+	got := decodeEvents(t, buf)
+	for _, name := range []string{"transfer_started", "progress", "received", "ack_received", "burned"} {
+		if len(got[name]) == 0 {
+			t.Errorf("expected a %s event, got %v", name, got)
+		}
+	}
+	// Both ends report progress; the sender's names the peer it serves.
+	var sent, received []event
+	for _, p := range got["progress"] {
+		if p.Identity != "" {
+			sent = append(sent, p)
+		} else {
+			received = append(received, p)
+		}
+	}
+	for side, p := range map[string][]event{"send": sent, "receive": received} {
+		if len(p) == 0 || p[len(p)-1].Bytes != 16 || p[len(p)-1].Total != 16 {
+			t.Errorf("expected %s progress to end at 16 of 16 bytes, got %+v", side, p)
+		}
+	}
+	if r := got["received"]; len(r) > 0 && (r[0].Digest == "" || len(r[0].Paths) != 1) {
+		t.Errorf("expected the digest and path in received, got %+v", r[0])
+	}
+}
+
+func TestBlockedAttemptEvent(t *testing.T) {
+	buf := captureEvents(t)
+	h := hostOffer(t, "mallory@example.com")
+
+	err := receive(context.Background(), h.Links()[0].URL, receiveOptions(t.TempDir(), ""))
+	if code := errExitCode(err); code != exitAuth {
+		t.Fatalf("expected exit code %d for a refused receive, got %d (%v)", exitAuth, code, err)
+	}
+	blocked := decodeEvents(t, buf)["blocked_attempt"]
+	if len(blocked) != 1 || blocked[0].Identity != "mallory@example.com" || blocked[0].Node != "laptop" {
+		t.Fatalf("expected one blocked_attempt from mallory's laptop, got %+v", blocked)
+	}
+}
+
+func TestShowSecret(t *testing.T) {
+	var out bytes.Buffer
+	showSecret(&out, []byte("correct horse battery staple"))
+	if want := "🔑 Secret (shown once):\ncorrect horse battery staple\n"; out.String() != want {
+		t.Fatalf("showSecret wrote %q, want %q", out.String(), want)
+	}
+}
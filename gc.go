@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"tail-burn/burn"
+)
+
+// --- CLEANUP & GC ---
+// Ctrl-C and SIGTERM are routed through the normal shutdown path so that an
+// ephemeral node's state directory is shredded; a crash or SIGKILL still
+// leaves one behind, which tail-burn gc removes (see burn.StaleStateDirs).
+
+// interruptReason is why an offer burned on SIGINT/SIGTERM.
+const interruptReason = "Interrupted"
+
+// interruptible routes the first SIGINT/SIGTERM into shutdownSignal, so
+// Ctrl-C burns an offer the same way an ACK or the deadline does. The
+// context ends with it (to abort joining the tailnet). A second signal
+// kills the process as usual.
+func interruptible(shutdownSignal chan string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			signal.Stop(sig)
+			cancel()
+			select {
+			case shutdownSignal <- interruptReason:
+			default:
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
+func runGC() {
+	gcCmd := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := gcCmd.Bool("dry-run", false, "Only list what would be removed")
+	minAge := gcCmd.Duration("min-age", 24*time.Hour, "Leave unmarked directories (from older versions) younger than this alone")
+	gcCmd.Parse(os.Args[2:])
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	stale, err := burn.StaleStateDirs(configDir, *minAge)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(stale) == 0 {
+		fmt.Println("✨ No stale tail-burn state found.")
+		return
+	}
+	for _, dir := range stale {
+		if *dryRun {
+			fmt.Printf("🗑️  Would remove %s\n", dir)
+			continue
+		}
+		if err := burn.ShredDir(dir); err != nil {
+			log.Printf("❌ Failed to remove %s: %v", dir, err)
+			continue
+		}
+		fmt.Printf("🔥 Removed %s\n", dir)
+	}
+}
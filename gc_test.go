@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInterruptibleRoutesSignal(t *testing.T) {
+	shutdown := make(chan string, 1)
+	ctx, stop := interruptible(shutdown)
+	defer stop()
+
+	self, _ := os.FindProcess(os.Getpid())
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot signal ourselves: %v", err)
+	}
+	select {
+	case reason := <-shutdown:
+		if reason != interruptReason {
+			t.Fatalf("unexpected shutdown reason %q", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected SIGINT to reach the shutdown signal")
+	}
+	if ctx.Err() == nil {
+		t.Fatalf("expected the context to end with the signal")
+	}
+}
+
+func TestInterruptibleStopIsQuiet(t *testing.T) {
+	shutdown := make(chan string, 1)
+	ctx, stop := interruptible(shutdown)
+	stop()
+	<-ctx.Done()
+	select {
+	case reason := <-shutdown:
+		t.Fatalf("expected no shutdown after stop, got %q", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
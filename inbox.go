@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+	"tail-burn/burn"
+)
+
+// --- INBOX ---
+// tail-burn inbox lists the offers waiting for us on the tailnet (see
+// burn.Inbox) and receives the one picked.
+
+// printOffers lists entries, numbered from 1.
+func printOffers(w io.Writer, entries []burn.InboxEntry) {
+	for i, e := range entries {
+		size := "unknown size"
+		if e.Size >= 0 {
+			size = burn.FormatBytes(e.Size)
+		}
+		lock := ""
+		if e.Encrypted {
+			lock = " 🔐"
+		}
+		fmt.Fprintf(w, "  [%d] %s (%s, %s)%s\n", i+1, e.Name, e.Class, size, lock)
+		opens := ""
+		if time.Now().Before(e.NotBefore) {
+			opens = fmt.Sprintf("opens in %s, ", burn.FormatRemaining(time.Until(e.NotBefore)))
+		}
+		fmt.Fprintf(w, "      from %s on %s, %sburns in %s\n", e.Owner, e.Host, opens, burn.FormatRemaining(time.Until(e.Expires)))
+	}
+}
+
+// pickOffer asks which entry to accept; -1 means none.
+func pickOffer(in io.Reader, out io.Writer, n int) (int, error) {
+	fmt.Fprintf(out, "Accept which offer? [1-%d, Enter to quit]: ", n)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return -1, nil
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return -1, nil
+	}
+	i, err := strconv.Atoi(line)
+	if err != nil || i < 1 || i > n {
+		return -1, fmt.Errorf("no offer %q", line)
+	}
+	return i - 1, nil
+}
+
+func runInbox() {
+	inboxCmd := flag.NewFlagSet("inbox", flag.ExitOnError)
+	destDir := inboxCmd.String("dir", ".", "Destination directory for an accepted offer")
+	accept := inboxCmd.Int("accept", 0, "Accept offer N without asking (0 = ask on a terminal)")
+	stallTimeout := inboxCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort when the server sends no data for this long (0 = never)")
+	inboxCmd.Parse(os.Args[2:])
+
+	opts := receiveOptions(*destDir, "")
+	opts.Stall = burn.StallPolicy{Idle: *stallTimeout}
+
+	fmt.Println("📬 Checking the tailnet for offers...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	entries, err := burn.Inbox(ctx, opts)
+	cancel()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("📭 No offers for you.")
+		return
+	}
+	printOffers(os.Stdout, entries)
+
+	choice := *accept - 1
+	if *accept == 0 {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return
+		}
+		if choice, err = pickOffer(os.Stdin, os.Stdout, len(entries)); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	} else if choice < 0 || choice >= len(entries) {
+		log.Fatalf("❌ No offer %d", *accept)
+	}
+	if choice < 0 {
+		return
+	}
+	if err := receive(context.Background(), entries[choice].URL(), opts); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPickOffer(t *testing.T) {
+	var out strings.Builder
+	for in, want := range map[string]int{"2\n": 1, "\n": -1, "": -1} {
+		got, err := pickOffer(strings.NewReader(in), &out, 3)
+		if err != nil || got != want {
+			t.Errorf("pickOffer(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := pickOffer(strings.NewReader("9\n"), &out, 3); err == nil {
+		t.Errorf("expected an out-of-range choice to fail")
+	}
+}
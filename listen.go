@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"tail-burn/burn"
+)
+
+// --- DROP BOX (Listener) ---
+// tail-burn listen runs a long-lived node that accepts offers pushed by
+// allowlisted senders (see burn.Server.HandlePushes) and receives each one,
+// pinned to the node that pushed it.
+
+// runHook runs the post-receive hook with the saved paths as arguments.
+func runHook(hook string, from burn.Peer, paths []string) error {
+	cmd := exec.Command(hook, paths...)
+	cmd.Env = append(os.Environ(),
+		"TAIL_BURN_SENDER="+from.Name,
+		"TAIL_BURN_SENDER_NODE="+from.Node,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runListener() {
+	listenCmd := flag.NewFlagSet("listen", flag.ExitOnError)
+	destDir := listenCmd.String("dir", ".", "Where accepted drops are saved")
+	allowExpr := listenCmd.String("allow", "", "Who may push: login names (alice@ for any provider), tag:<tag>, node:<name>, id:<stable-id>, any-of(...)/all-of(...)")
+	hook := listenCmd.String("hook", "", "Command to run after each verified drop (saved paths as arguments)")
+	hostname := listenCmd.String("hostname", "tail-burn-drop", "Name this listener advertises on the tailnet")
+	authKey := listenCmd.String("authkey", "", "Auth key for the listener's node (default: TS_AUTHKEY)")
+	stallTimeout := listenCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort a drop when the sender sends no data for this long (0 = never)")
+	minRate := listenCmd.Int64("min-rate", 0, "Abort a drop slower than this many bytes/s (0 = off)")
+	debugMode := listenCmd.Bool("debug", false, "Enable verbose Tailscale logs")
+	listenCmd.Parse(os.Args[2:])
+
+	if *allowExpr == "" {
+		fmt.Println("Usage: tail-burn listen -allow=<user@provider>[,tag:build,...] [-dir=<dest>] [-hook=<cmd>] [-hostname=<name>]")
+		os.Exit(1)
+	}
+	allow, err := burn.ParseTarget(*allowExpr)
+	if err != nil {
+		log.Fatalf("❌ Invalid -allow: %v", err)
+	}
+	if fi, err := os.Stat(*destDir); err != nil || !fi.IsDir() {
+		log.Fatalf("❌ Destination is not a directory: %s", *destDir)
+	}
+	key := *authKey
+	if key == "" {
+		key = os.Getenv("TS_AUTHKEY")
+	}
+
+	// A long-lived node: its state (and name) survive restarts
+	configDir, _ := os.UserConfigDir()
+	s := burn.NewNode(*hostname, filepath.Join(configDir, "tsnet-"+*hostname), key, *debugMode)
+	defer s.Close()
+	localClient, err := s.LocalClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &burn.Server{Client: localClient}
+	srv.HandlePushes(allow, func(from burn.Peer, link string) {
+		opts := burn.ReceiveOptions{
+			Dir:    *destDir,
+			Stall:  burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate},
+			From:   burn.SenderCheck{Node: from.NodeID},
+			Client: localClient,
+			Dial:   s.Dial,
+			Status: os.Stdout,
+		}
+		res, err := burn.Receive(context.Background(), link, opts)
+		if err != nil {
+			log.Printf("❌ Drop from %s failed: %v", from.Name, err)
+			return
+		}
+		log.Printf("✅ Drop from %s saved.", from.Name)
+		if *hook != "" && len(res.Paths) > 0 {
+			log.Printf("🪝 Running hook for %s...", strings.Join(res.Paths, ", "))
+			if err := runHook(*hook, from, res.Paths); err != nil {
+				log.Printf("❌ Hook failed: %v", err)
+			}
+		}
+	})
+
+	fmt.Println("🔌 Joining tailnet...")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ln, err := srv.ListenTailnet(ctx, s)
+	cancel()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	fmt.Println("🔥 \033[1mtail-burn\033[0m (Listener Mode)")
+	fmt.Println("-------------------------------------------")
+	fmt.Printf("📂 Saving to: %s\n", *destDir)
+	fmt.Printf("👤 Allowed:   %s\n", allow)
+	if *hook != "" {
+		fmt.Printf("🪝 Hook:      %s\n", *hook)
+	}
+	fmt.Println("-------------------------------------------")
+	fmt.Printf("📮 Listening at %s\n", srv.BaseURL)
+	fmt.Printf("💻 Push with: \033[33mtail-burn send -to-node=%s <path>\033[0m\n", *hostname)
+
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			log.Printf("❌ Server error: %v", err)
+		}
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	fmt.Println("\n🛑 Shutting down listener")
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tail-burn/burn"
+)
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	hook := filepath.Join(dir, "hook.sh")
+	os.WriteFile(hook, []byte("#!/bin/sh\necho \"$TAIL_BURN_SENDER $TAIL_BURN_SENDER_NODE $@\" > "+out+"\n"), 0700)
+
+	if err := runHook(hook, burn.Peer{Name: "alice@example.com", Node: "tail-burn-ab12"}, []string{"/srv/drops/a", "/srv/drops/b"}); err != nil {
+		t.Fatalf("runHook: %v", err)
+	}
+	got, _ := os.ReadFile(out)
+	if want := "alice@example.com tail-burn-ab12 /srv/drops/a /srv/drops/b\n"; string(got) != want {
+		t.Fatalf("hook saw %q, want %q", got, want)
+	}
+}
@@ -2,124 +2,18 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"flag"
 	"fmt"
-	"html/template"
 	"io"
 	"log"
-	"net/http"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
 
-	"tailscale.com/client/tailscale/apitype"
-	"tailscale.com/ipn/ipnstate"
-	"tailscale.com/tsnet"
+	"golang.org/x/term"
+	"tail-burn/burn"
 )
 
-// --- HTML TEMPLATE (Browser Fallback with UI Fix) ---
-const htmlTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>Tail-Burn</title>
-    <style>
-        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
-        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
-        h1 { font-size: 24px; margin-bottom: 10px; }
-        p { color: #52525b; margin-bottom: 30px; }
-        .file-info { background: #f4f4f5; padding: 15px; border-radius: 8px; margin-bottom: 25px; font-family: monospace; font-size: 14px; text-align: left; }
-        .btn { background: #ef4444; color: white; border: none; padding: 12px 24px; border-radius: 6px; font-size: 16px; font-weight: 600; cursor: pointer; width: 100%; transition: background 0.2s; }
-        .btn:hover { background: #dc2626; }
-        .btn:disabled { background: #a1a1aa; cursor: not-allowed; }
-        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
-        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
-        .hidden { display: none; }
-    </style>
-    <script>
-        function triggerBurn() {
-            var btn = document.getElementById('dlBtn');
-            var card = document.getElementById('mainContent');
-            var done = document.getElementById('doneState');
-            
-            // 1. Disable button immediately
-            btn.disabled = true;
-            btn.innerText = "Downloading...";
-            
-            // 2. Wait 1 second (ensure POST submits), then show Done state
-            setTimeout(function() {
-                card.classList.add('hidden');
-                done.classList.remove('hidden');
-            }, 1000);
-        }
-    </script>
-</head>
-<body>
-    <div class="card">
-        <div id="mainContent">
-            <h1>🔥 Secure Drop</h1>
-            <p><b>{{.Sender}}</b> sent a file.</p>
-            <div class="file-info">
-                <div>📄 <b>{{.FileName}}</b></div>
-                <div>📦 <b>{{.FileSize}}</b></div>
-            </div>
-            <form method="POST" onsubmit="triggerBurn()">
-                <button id="dlBtn" type="submit" class="btn">Download & Destroy</button>
-            </form>
-            <div class="footer">⚠️ One-time use link.</div>
-        </div>
-
-        <div id="doneState" class="hidden">
-            <span class="success-icon">💥</span>
-            <h1>File Burned</h1>
-            <p>The file has been downloaded and the server is self-destructing.</p>
-            <div class="footer">You may close this tab.</div>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-// --- HTML TEMPLATE (Burned Link) ---
-const burnedHTMLTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>Tail-Burn</title>
-    <style>
-        body { font-family: -apple-system, system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; color: #18181b; }
-        .card { background: white; padding: 40px; border-radius: 12px; box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1); text-align: center; max-width: 400px; width: 100%; }
-        h1 { font-size: 24px; margin-bottom: 10px; }
-        p { color: #52525b; margin-bottom: 30px; }
-        .success-icon { font-size: 48px; display: block; margin-bottom: 20px; }
-        .footer { margin-top: 20px; font-size: 12px; color: #a1a1aa; }
-    </style>
-</head>
-<body>
-    <div class="card">
-        <span class="success-icon">💥</span>
-        <h1>Link Burned</h1>
-        <p>This link has already been used or is no longer available.</p>
-        <div class="footer">Please request a new link.</div>
-    </div>
-</body>
-</html>
-`
-
-type tailBurnClient interface {
-	WhoIs(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error)
-	Status(ctx context.Context) (*ipnstate.Status, error)
-}
-
-var landingTemplate = template.Must(template.New("landing").Parse(htmlTemplate))
-var burnedTemplate = template.Must(template.New("burned").Parse(burnedHTMLTemplate))
-var browserShutdownDelay = 5 * time.Second
-
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
@@ -128,9 +22,29 @@ func main() {
 
 	switch os.Args[1] {
 	case "send":
-		runSender()
+		os.Exit(runSender())
 	case "receive":
-		runReceiver()
+		os.Exit(runReceiver())
+	case "request":
+		runRequester()
+	case "fulfill":
+		runFulfill()
+	case "inbox":
+		runInbox()
+	case "listen":
+		runListener()
+	case "gc":
+		runGC()
+	case "daemon":
+		os.Exit(runDaemon())
+	case "list":
+		os.Exit(runList())
+	case "revoke":
+		os.Exit(runRevoke())
+	case "status":
+		os.Exit(runStatus())
+	case "audit":
+		os.Exit(runAudit())
 	default:
 		printUsage()
 	}
@@ -138,410 +52,506 @@ func main() {
 
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  tail-burn send -target=<user> [-wipe] <file>   # Host a file")
-	fmt.Println("  tail-burn receive <url>                        # Download a file")
+	fmt.Println("  tail-burn send -target=<user> [-wipe] <path>...   # Host a file, directory or bundle")
+	fmt.Println("  tail-burn receive [-dir=<dest>] <url|code>        # Download a file")
+	fmt.Println("  tail-burn inbox [-dir=<dest>]                     # List and accept offers for you")
+	fmt.Println("  tail-burn listen -allow=<user> [-dir=<dest>]      # Accept pushed drops")
+	fmt.Println("  tail-burn gc [-dry-run]                           # Remove node state left by crashes")
+	fmt.Println("  tail-burn request -from=<user> [-dir=<dest>]      # Ask someone for a file")
+	fmt.Println("  tail-burn fulfill <url> <file>                    # Answer a request")
+	fmt.Println("  tail-burn daemon [-idle=<duration>]               # Host offers on one long-lived node")
+	fmt.Println("  tail-burn list | status | revoke <id>             # Manage the daemon's offers")
+	fmt.Println("  tail-burn audit verify <file>                     # Check an -audit log for tampering")
 }
 
 // ==========================================
 // SERVER LOGIC (Sender)
 // ==========================================
-func runSender() {
+// runSender returns the exit code for how the offer burned.
+func runSender() int {
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
-	targetUser := sendCmd.String("target", "", "Tailscale login name")
-	timeoutMinutes := sendCmd.Int("timeout", 10, "Minutes before auto-burn")
+	targetExpr := sendCmd.String("target", "", "Who may download: login names, tag:<tag>, node:<name>, id:<stable-id>, any-of(...)/all-of(...)")
+	var expires string
+	sendCmd.StringVar(&expires, "expires", "10m", "When the offer burns: a duration (90s, 36h) or a time (2026-10-20T18:00Z)")
+	sendCmd.StringVar(&expires, "timeout", "10m", "Deprecated: same as -expires (a bare number is minutes)")
+	notBefore := sendCmd.String("not-before", "", "Embargo: refuse downloads until this time (or duration from now)")
 	debugMode := sendCmd.Bool("debug", false, "Enable verbose Tailscale logs")
 	wipe := sendCmd.Bool("wipe", false, "Delete source file after successful transfer")
+	stallTimeout := sendCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort a transfer when the receiver takes no data for this long (0 = never)")
+	minRate := sendCmd.Int64("min-rate", 0, "Abort a transfer slower than this many bytes/s (0 = off)")
+	encrypt := sendCmd.Bool("encrypt", false, "End-to-end encrypt with a key that only exists in the link")
+	acl := sendCmd.Bool("acl", false, "Require a "+string(burn.Capability)+" grant from the tailnet policy (on top of -target, if given)")
+	textMode := sendCmd.Bool("text", false, "Send a one-time secret read from stdin or a hidden prompt (never from argv)")
+	streamName := sendCmd.String("name", "stdin", "File name the receiver saves a stream (send -) as")
+	perRecipient := sendCmd.Bool("per-recipient", false, "Give every -target term its own link and burn state; finish when all have collected")
+	toNode := sendCmd.String("to-node", "", "Push the offer to a tail-burn listener on this node (it becomes the target unless -target/-acl are given)")
+	codeAttempts := sendCmd.Int("code-attempts", burn.DefaultCodeAttempts, "Short-code exchanges allowed before the offer burns (0 = no short code)")
+	output := sendCmd.String("output", "text", "text, or json for newline-delimited events on stdout")
+	viaDaemon := sendCmd.Bool("via-daemon", false, "Hand the offer to a running tail-burn daemon instead of starting a node (no short code)")
+	socket := sendCmd.String("socket", burn.DefaultControlSocket(), "The daemon's control socket (-via-daemon)")
+	auditPath := sendCmd.String("audit", "", "Append a hash-chained JSONL record of every access to this file")
 
 	sendCmd.Parse(os.Args[2:])
-	filePath := sendCmd.Arg(0)
+	paths := sendCmd.Args()
+	if err := setOutput(*output); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ui := human()
 
-	if *targetUser == "" || filePath == "" {
-		fmt.Println("Usage: tail-burn send -target=<user@provider> [-wipe] <file_path>")
+	if *toNode != "" && *targetExpr == "" && !*acl {
+		*targetExpr = "node:" + *toNode
+	}
+	if (*targetExpr == "" && !*acl) || (len(paths) == 0 && !*textMode) {
+		fmt.Fprintln(ui, "Usage: tail-burn send {-target=<user@provider>[,tag:ci,...] | -acl} [-wipe] <path>...")
+		fmt.Fprintln(ui, "       tail-burn send {-target=... | -acl} [-name=<file>] - < stream")
+		fmt.Fprintln(ui, "       tail-burn send {-target=... | -acl} -text < secret.txt")
+		fmt.Fprintln(ui, "       tail-burn send -to-node=<listener> <path>...")
+		fmt.Fprintln(ui, "       tail-burn send -via-daemon {-target=... | -acl} <path>...")
 		os.Exit(1)
 	}
+	streaming := len(paths) == 1 && paths[0] == "-"
 
-	// File Prep
-	stat, err := os.Stat(filePath)
-	if err != nil {
-		log.Fatalf("❌ Error stating file: %v", err)
+	// Fail fast on a bad schedule; it is resolved again once the payload is
+	// hashed, so hashing a large payload doesn't eat into a relative -expires
+	if _, _, err := burn.ParseSchedule(*notBefore, expires, time.Now()); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *notBefore != "" && *toNode != "" {
+		log.Fatalf("❌ -not-before can't be combined with -to-node; the listener downloads right away")
+	}
+	if *viaDaemon && (streaming || *toNode != "") {
+		log.Fatalf("❌ -via-daemon can't be combined with a stream or -to-node")
+	}
+	if *viaDaemon && *auditPath != "" {
+		log.Fatalf("❌ -audit goes on the daemon with -via-daemon; it sees the requests")
 	}
-	fileSize := formatBytes(stat.Size())
-	fileName := filepath.Base(filePath)
 
-	// Hostname & State
-	randSuffix := make([]byte, 2)
-	if _, err := rand.Read(randSuffix); err != nil {
-		log.Fatalf("❌ Error generating hostname suffix: %v", err)
+	offer := &burn.Offer{ACL: *acl, Encrypt: *encrypt}
+	if *textMode {
+		if len(paths) > 0 {
+			log.Fatalf("❌ -text reads the secret from stdin; don't put it on the command line")
+		}
+		if *encrypt || *wipe || *toNode != "" {
+			log.Fatalf("❌ -text can't be combined with -encrypt, -wipe or -to-node")
+		}
+		secret, err := readSecret(os.Stdin)
+		if err != nil {
+			log.Fatalf("❌ Error reading secret: %v", err)
+		}
+		defer clear(secret)
+		offer.Text = secret
+	} else if streaming {
+		if *wipe {
+			log.Fatalf("❌ -wipe needs a path; there is nothing to delete for a stream")
+		}
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			log.Fatalf("❌ send - streams stdin; pipe something into it")
+		}
+		offer.Stream, offer.Name = os.Stdin, *streamName
+	} else {
+		offer.Paths = paths
 	}
-	hostname := fmt.Sprintf("tail-burn-%x", randSuffix)
 
-	configDir, _ := os.UserConfigDir()
-	stateDir := filepath.Join(configDir, "tsnet-"+hostname)
+	// One share per recipient, or a single one for everyone -target allows
+	var err error
+	if *perRecipient {
+		if streaming || *toNode != "" {
+			log.Fatalf("❌ -per-recipient can't be combined with a stream or -to-node")
+		}
+		if offer.Recipients, err = burn.ParseTargets(*targetExpr); err != nil {
+			log.Fatalf("❌ Invalid -target: %v", err)
+		}
+	} else if *targetExpr != "" {
+		if offer.Target, err = burn.ParseTarget(*targetExpr); err != nil {
+			log.Fatalf("❌ Invalid -target: %v", err)
+		}
+	}
 
-	// --- LOGGING LOGIC ---
-	var tsLogf func(string, ...any)
-	if *debugMode {
-		tsLogf = log.Printf
-	} else {
-		tsLogf = func(string, ...any) {} // Silent
+	// The daemon parses the target and hashes the payload itself
+	if *viaDaemon {
+		return sendViaDaemon(*socket, burn.OfferSpec{
+			Paths:        offer.Paths,
+			Text:         offer.Text,
+			Target:       *targetExpr,
+			PerRecipient: *perRecipient,
+			ACL:          *acl,
+			Encrypt:      *encrypt,
+			Wipe:         *wipe,
+		}, *notBefore, expires)
 	}
 
-	s := &tsnet.Server{
-		Hostname:  hostname,
-		Dir:       stateDir,
-		Ephemeral: true,
-		AuthKey:   os.Getenv("TS_AUTHKEY"),
-		Logf:      tsLogf,
+	// Integrity: the smart client checks the link's digest, the landing page
+	// shows the browser's (a zip, for archives)
+	if len(offer.Paths) > 0 {
+		fmt.Fprintln(ui, "🔒 Hashing payload...")
+	}
+	if err := offer.Prepare(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if offer.NotBefore, offer.Expires, err = burn.ParseSchedule(*notBefore, expires, time.Now()); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	info := offer.Info()
+	audit := openAudit(*auditPath)
+	defer audit.Close()
+
+	// Short code: its number goes into the hostname so receive can find us
+	prefix := "tail-burn"
+	if *codeAttempts > 0 {
+		nameplate, code, err := burn.NewCode()
+		if err != nil {
+			log.Fatalf("❌ Error generating code: %v", err)
+		}
+		prefix = fmt.Sprintf("tail-burn-%d", nameplate)
+		offer.Code, offer.CodeAttempts = code, *codeAttempts
 	}
-	defer func() {
-		s.Close()
-		os.RemoveAll(stateDir)
-	}()
 
-	localClient, err := s.LocalClient()
+	// Hostname & State
+	s, cleanupNode, err := burn.NewEphemeralNode(prefix, os.Getenv("TS_AUTHKEY"), *debugMode)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("❌ Error creating node: %v", err)
 	}
-
-	// Generate Secret URL
-	randBytes := make([]byte, 12)
-	if _, err := rand.Read(randBytes); err != nil {
-		log.Fatalf("❌ Error generating secret path: %v", err)
+	defer cleanupNode()
+	// log.Fatalf skips defers; the node's state must not outlive us
+	fatalf := func(format string, v ...any) {
+		cleanupNode()
+		log.Fatalf(format, v...)
 	}
-	secretPath := "/" + hex.EncodeToString(randBytes)
-	ackPath := secretPath + "/ack" // The "Kill Switch" endpoint
 
 	shutdownSignal := make(chan string, 1) // Buffered channel to prevent blocking
+	// From here on, Ctrl-C burns the offer through shutdownSignal
+	interrupted, stopSignals := interruptible(shutdownSignal)
+	defer stopSignals()
 
-	// Handlers
-	mux := http.NewServeMux()
-	registerHandlers(mux, localClient, *targetUser, filePath, fileName, fileSize, shutdownSignal, secretPath, ackPath)
+	localClient, err := s.LocalClient()
+	if err != nil {
+		fatalf("❌ %v", err)
+	}
 
-	ln, err := s.Listen("tcp", ":80")
+	srv := newSendServer(localClient, burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate}, ui)
+	srv.Audit = audit
+
+	fmt.Fprintln(ui, "🔌 Joining tailnet...")
+	ctx, cancel := context.WithTimeout(interrupted, time.Until(offer.Expires))
+	ln, err := srv.ListenTailnet(ctx, s)
+	cancel()
 	if err != nil {
-		log.Fatal(err)
+		fatalf("❌ %v", err)
 	}
+	events.emit(event{Event: "node_up", Node: s.Hostname, URL: srv.BaseURL})
 
-	// FIX: Timeouts added for security
-	srv := &http.Server{
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       30 * time.Second,
-		WriteTimeout:      30 * time.Second,
-		IdleTimeout:       60 * time.Second,
+	hosted, err := srv.Add(offer)
+	if err != nil {
+		fatalf("❌ %v", err)
 	}
+	go func() {
+		select {
+		case reason := <-shutdownSignal:
+			hosted.Burn(reason)
+		case <-hosted.Done():
+		}
+	}()
 
 	// UI Output
-	fmt.Print("\033[H\033[2J")
-	fmt.Println("🔥 \033[1mtail-burn\033[0m (Server Mode)")
-	fmt.Println("-------------------------------------------")
-	fmt.Printf("📦 File: %s (%s)\n", fileName, fileSize)
-	fmt.Printf("👤 Target: %s\n", *targetUser)
+	fileSize := burn.FormatBytes(info.Size)
+	if streaming {
+		fileSize = "unknown size (streamed)"
+	}
+	if events == nil {
+		fmt.Fprint(ui, "\033[H\033[2J")
+	}
+	fmt.Fprintln(ui, "🔥 \033[1mtail-burn\033[0m (Server Mode)")
+	fmt.Fprintln(ui, "-------------------------------------------")
+	if *textMode {
+		fmt.Fprintf(ui, "🔑 Secret: %s (reveal once)\n", fileSize)
+	} else if streaming {
+		fmt.Fprintf(ui, "📦 Stream: %s (from stdin, %s)\n", info.Name, fileSize)
+	} else if info.Class == burn.ClassArchive {
+		fmt.Fprintf(ui, "📦 Archive: %s (%s, %d path(s))\n", info.Name, fileSize, len(paths))
+	} else {
+		fmt.Fprintf(ui, "📦 File: %s (%s)\n", info.Name, fileSize)
+	}
+	if streaming {
+		fmt.Fprintln(ui, "🔒 SHA-256: sent as a trailer once the stream ends")
+	} else {
+		fmt.Fprintf(ui, "🔒 SHA-256: %s\n", offer.Digest())
+	}
+	if *encrypt {
+		fmt.Fprintln(ui, "🔐 Encryption: end-to-end (key is only in the link)")
+	}
+	links := hosted.Links()
+	if *perRecipient {
+		fmt.Fprintf(ui, "👥 Recipients: %d (each gets its own link)\n", len(links))
+	} else {
+		fmt.Fprintf(ui, "👤 Target: %s\n", links[0].Recipient)
+	}
+	if time.Now().Before(offer.NotBefore) {
+		fmt.Fprintf(ui, "🔓 Opens: %s (in %s)\n", offer.NotBefore.Format("2006-01-02 15:04:05 MST"), burn.FormatRemaining(time.Until(offer.NotBefore)))
+	}
+	fmt.Fprintf(ui, "⏳ Burns: %s (in %s)\n", offer.Expires.Format("2006-01-02 15:04:05 MST"), burn.FormatRemaining(time.Until(offer.Expires)))
 	if *wipe {
-		fmt.Println("⚠️  MODE: \033[31mWIPE ENABLED (File will be deleted)\033[0m")
+		fmt.Fprintln(ui, "⚠️  MODE: \033[31mWIPE ENABLED (File will be deleted)\033[0m")
+	}
+	fmt.Fprintln(ui, "-------------------------------------------")
+	for _, l := range links {
+		printLink(ui, l, *perRecipient)
+		ready := event{
+			Event:      "link_ready",
+			URL:        l.URL,
+			BrowserURL: l.BrowserURL,
+			Code:       offer.Code,
+			NotBefore:  offer.NotBefore,
+			Expires:    offer.Expires,
+			Name:       info.Name,
+			Size:       info.Size,
+			Digest:     offer.Digest(),
+		}
+		if *perRecipient {
+			ready.Recipient = l.Recipient
+		}
+		events.emit(ready)
 	}
-	fmt.Println("-------------------------------------------")
-	url := fmt.Sprintf("http://%s%s", hostname, secretPath)
-	fmt.Printf("🌐 Browser Link: \033[32m%s\033[0m\n", url)
-	fmt.Printf("💻 Command:      \033[33mtail-burn receive %s\033[0m\n", url)
-	fmt.Println("\n(Waiting...)")
+	if offer.Code != "" {
+		fmt.Fprintf(ui, "🪄 Code:         \033[35mtail-burn receive %s\033[0m (%d attempt(s))\n", offer.Code, *codeAttempts)
+	}
+	fmt.Fprintln(ui, "\n(Waiting...)")
 
 	go func() {
-		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(ln); err != nil {
 			log.Printf("❌ Server error: %v", err)
 		}
 	}()
 
-	// Doomsday Timer
-	go func() {
-		time.Sleep(time.Duration(*timeoutMinutes) * time.Minute)
-		select {
-		case shutdownSignal <- "Timeout reached":
-		default:
-		}
-	}()
+	// Push straight to a listener: it downloads like any receiver would
+	if *toNode != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := burn.Push(ctx, s.HTTPClient(), *toNode, links[0].URL); err != nil {
+				log.Printf("❌ %v", err)
+				hosted.Burn(pushReason)
+				return
+			}
+			fmt.Fprintf(ui, "📨 Pushed to listener %s\n", *toNode)
+			events.emit(event{Event: "pushed", Node: *toNode})
+		}()
+	}
 
-	reason := <-shutdownSignal
-	fmt.Printf("\n🛑 Shutting down: %s\n", reason)
+	<-hosted.Done()
+	reason := hosted.Reason()
+	fmt.Fprintf(ui, "\n🛑 Shutting down: %s\n", reason)
+	events.emit(event{Event: "burned", Reason: reason})
+	if *perRecipient {
+		got, pending := hosted.Collected()
+		if len(got) > 0 {
+			fmt.Fprintf(ui, "📋 Collected:     %s\n", strings.Join(got, ", "))
+		}
+		if len(pending) > 0 {
+			fmt.Fprintf(ui, "⏳ Not collected: %s\n", strings.Join(pending, ", "))
+		}
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	srv.Shutdown(ctx)
 
 	// --- WIPE LOGIC RESTORED ---
-	if *wipe {
-		fmt.Println("🔥 Deleting source file...")
-		// We can safely remove because server shutdown ensures file handles are closed
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("❌ Failed to wipe file: %v", err)
-		} else {
-			fmt.Println("✅ Source file deleted.")
+	if *wipe && wipeSources(ui, reason, paths) {
+		events.emit(event{Event: "wiped", Paths: paths})
+		if err := audit.Record(burn.AuditEntry{Event: "wiped", Offer: hosted.ID(), Paths: paths}); err != nil {
+			log.Printf("❌ Cannot write audit entry: %v", err)
 		}
 	}
+	return exitCode(reason)
 }
 
-func registerHandlers(
-	mux *http.ServeMux,
-	localClient tailBurnClient,
-	targetUser string,
-	filePath string,
-	fileName string,
-	fileSize string,
-	shutdownSignal chan string,
-	secretPath string,
-	ackPath string,
-) {
-	var used atomic.Bool
-	var inProgress atomic.Bool
-
-	// 1. The ACK Handler (Smart Client Kill Switch)
-	mux.HandleFunc(ackPath, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			log.Println("⚡️ ACK received from smart client.")
-			w.Write([]byte("OK"))
-			used.Store(true)
-			select {
-			case shutdownSignal <- "Client confirmed receipt":
-			default:
-			}
-		}
-	})
-
-	// 2. The Main Handler (Download)
-	mux.HandleFunc(secretPath, func(w http.ResponseWriter, r *http.Request) {
-		who, err := localClient.WhoIs(r.Context(), r.RemoteAddr)
-		if err != nil {
-			http.Error(w, "Identity Error", 500)
-			return
-		}
-		if !strings.EqualFold(who.UserProfile.LoginName, targetUser) {
-			log.Printf("⛔️ BLOCKED: %s", who.UserProfile.LoginName)
-			http.Error(w, "Forbidden", 403)
-			return
-		}
-
-		// Detect if it's our smart client
-		isSmartClient := r.Header.Get("X-Tail-Burn-Client") == "true"
-
-		if used.Load() {
-			if !isSmartClient {
-				w.WriteHeader(http.StatusGone)
-				_ = burnedTemplate.Execute(w, nil)
-				return
-			}
-			http.Error(w, "Gone", http.StatusGone)
-			return
-		}
-
-		if r.Method == "GET" && !isSmartClient {
-			// Browser: Show HTML
-			sender := "A Tailscale User"
-			st, err := localClient.Status(r.Context())
-			if err == nil && st != nil && st.Self != nil {
-				myUserID := st.Self.UserID
-				if profile, ok := st.User[myUserID]; ok {
-					sender = profile.LoginName
-				}
-			}
-
-			if err := landingTemplate.Execute(w, struct{ Sender, FileName, FileSize string }{sender, fileName, fileSize}); err != nil {
-				http.Error(w, "Template Error", http.StatusInternalServerError)
-				return
-			}
-			return
+// wipeSources deletes paths for send -wipe, but only if reason says the
+// offer was delivered. It reports whether they are all gone.
+func wipeSources(ui io.Writer, reason string, paths []string) bool {
+	if !burn.Delivered(reason) {
+		fmt.Fprintf(ui, "⚠️  Not delivered (%s): keeping the source file.\n", reason)
+		return false
+	}
+	fmt.Fprintln(ui, "🔥 Deleting source file...")
+	// We can safely remove because server shutdown ensures file handles are closed
+	wiped := true
+	for _, p := range paths {
+		if err := os.RemoveAll(p); err != nil {
+			log.Printf("❌ Failed to wipe %s: %v", p, err)
+			wiped = false
 		}
+	}
+	if wiped {
+		fmt.Fprintln(ui, "✅ Source file deleted.")
+	}
+	return wiped
+}
 
-		if r.Method == "POST" || (r.Method == "GET" && isSmartClient) {
-			if !inProgress.CompareAndSwap(false, true) {
-				if !isSmartClient {
-					w.WriteHeader(http.StatusGone)
-					_ = burnedTemplate.Execute(w, nil)
-					return
-				}
-				http.Error(w, "Gone", http.StatusGone)
-				return
-			}
-			success := false
-			defer func() {
-				if !success {
-					inProgress.Store(false)
-				}
-			}()
-
-			log.Printf("🚀 Sending file to %s...", who.UserProfile.LoginName)
-
-			// Open file fresh for every request
-			file, err := os.Open(filePath)
-			if err != nil {
-				http.Error(w, "File Error", http.StatusInternalServerError)
-				return
-			}
-			defer file.Close()
-
-			fi, err := file.Stat()
-			if err != nil {
-				http.Error(w, "File Error", http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
-			w.Header().Set("Content-Type", "application/octet-stream")
-			w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
-
-			if _, err := io.Copy(w, file); err != nil {
-				log.Printf("❌ Transfer failed: %v", err)
-				return
-			}
-			if f, ok := w.(http.Flusher); ok {
-				f.Flush()
-			}
-
-			inProgress.Store(false)
-			success = true
-
-			// If it's a browser (POST), we have to guess when to shut down
-			if !isSmartClient {
-				used.Store(true)
-				log.Println("🔥 Browser transfer complete. Starting timer...")
-				go func() {
-					time.Sleep(browserShutdownDelay)
-					select {
-					case shutdownSignal <- "Browser download finished":
-					default:
-					}
-				}()
-			}
-			// If it's a smart client, we do NOTHING here. We wait for the /ack POST.
-		}
-	})
+// newSendServer is the server send hosts its offer on, reporting to the
+// screen (ui) and the event stream.
+func newSendServer(client burn.LocalClient, stall burn.StallPolicy, ui io.Writer) *burn.Server {
+	return &burn.Server{
+		Client: client,
+		Stall:  stall,
+		OnBlocked: func(_ *burn.Hosted, p burn.Peer) {
+			events.emit(peerEvent("blocked_attempt", p))
+		},
+		OnTransfer: func(_ *burn.Hosted, p burn.Peer) {
+			events.emit(peerEvent("transfer_started", p))
+		},
+		OnProgress: func(_ *burn.Hosted, p burn.Peer, sent, total int64) {
+			e := peerEvent("progress", p)
+			e.Bytes, e.Total = sent, total
+			events.emit(e)
+		},
+		OnAck: func(_ *burn.Hosted, p burn.Peer) {
+			events.emit(peerEvent("ack_received", p))
+		},
+		OnCollected: func(h *burn.Hosted, recipient, reason string) {
+			got, pending := h.Collected()
+			fmt.Fprintf(ui, "✅ %s: %s (%d/%d)\n", recipient, reason, len(got), len(got)+len(pending))
+			events.emit(event{Event: "collected", Recipient: recipient, Reason: reason})
+		},
+	}
 }
 
 // ==========================================
 // CLIENT LOGIC (Receiver)
 // ==========================================
-func runReceiver() {
+// runReceiver returns the exit code for how the receive ended.
+func runReceiver() int {
 	recvCmd := flag.NewFlagSet("receive", flag.ExitOnError)
+	destDir := recvCmd.String("dir", ".", "Destination directory")
+	output := recvCmd.String("o", "", "Save to this file instead of under -dir (- for stdout)")
+	resume := recvCmd.Bool("resume", false, "Continue an interrupted download from its .part file")
+	stallTimeout := recvCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort when the server sends no data for this long (0 = never)")
+	minRate := recvCmd.Int64("min-rate", 0, "Abort a download slower than this many bytes/s (0 = off)")
+	fromLogin := recvCmd.String("from", "", "Only download from a tail-burn node owned by this login")
+	fromTag := recvCmd.String("from-tag", "", "Only download from a tail-burn node carrying this tag")
+	useTsnet := recvCmd.Bool("tsnet", false, "Join the tailnet with an own ephemeral node (auth key from -authkey or TS_AUTHKEY)")
+	authKey := recvCmd.String("authkey", "", "Auth key for an own ephemeral node (implies -tsnet)")
+	debugMode := recvCmd.Bool("debug", false, "Enable verbose Tailscale logs")
+	outputFormat := recvCmd.String("output", "text", "text, or json for newline-delimited events on stdout")
 	recvCmd.Parse(os.Args[2:])
 	url := recvCmd.Arg(0)
+	if err := setOutput(*outputFormat); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
 	if url == "" {
-		fmt.Println("Usage: tail-burn receive <url>")
+		fmt.Println("Usage: tail-burn receive [-dir=<dest> | -o=<file|->] [-resume] [-from=<login>] [-from-tag=<tag>] [-tsnet|-authkey=<key>] [-output=json] <url|code>")
 		os.Exit(1)
 	}
-
-	if err := receive(url); err != nil {
-		log.Fatalf("❌ %v", err)
+	if events != nil && *output == "-" {
+		log.Fatalf("❌ -output=json puts events on stdout; it can't be combined with -o -")
 	}
-}
-
-func receive(url string) error {
-	fmt.Println("🔍 Connecting to tail-burn server...")
 
-	// 1. Start Download Request
-	client := &http.Client{Timeout: 60 * time.Second}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("bad request URL: %w", err)
+	opts := receiveOptions(*destDir, *output)
+	opts.Resume = *resume
+	opts.Stall = burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate}
+	opts.From = burn.SenderCheck{Login: *fromLogin, Tag: *fromTag}
+
+	// No tailscaled here (CI, containers): bring our own node
+	cleanupNode := func() {}
+	if *useTsnet || *authKey != "" {
+		key := *authKey
+		if key == "" {
+			key = os.Getenv("TS_AUTHKEY")
+		}
+		if key == "" {
+			log.Fatalf("❌ -tsnet needs -authkey or TS_AUTHKEY")
+		}
+		s, cleanup, err := burn.NewEphemeralNode("tail-burn-recv", key, *debugMode)
+		if err != nil {
+			log.Fatalf("❌ Error creating node: %v", err)
+		}
+		cleanupNode = cleanup
+
+		// Ctrl-C mid-transfer must still take the node down
+		interrupt := make(chan string, 1)
+		interrupted, stopSignals := interruptible(interrupt)
+		defer stopSignals()
+		go func() {
+			<-interrupt
+			fmt.Fprintln(os.Stderr, "\n🛑 Interrupted, removing the ephemeral node...")
+			events.emit(event{Event: "error", Error: interruptReason})
+			cleanupNode()
+			os.Exit(exitInterrupted)
+		}()
+
+		fmt.Fprintln(human(), "🔌 Joining tailnet as an ephemeral node...")
+		ctx, cancel := context.WithTimeout(interrupted, 2*time.Minute)
+		_, err = s.Up(ctx)
+		cancel()
+		if err != nil {
+			cleanupNode()
+			log.Fatalf("❌ Tailscale node did not come up: %v", err)
+		}
+		lc, err := s.LocalClient()
+		if err != nil {
+			cleanupNode()
+			log.Fatal(err)
+		}
+		opts.Client = lc
+		opts.Dial = s.Dial
+		events.emit(event{Event: "node_up", Node: s.Hostname})
 	}
-	req.Header.Set("X-Tail-Burn-Client", "true") // Identify ourselves
 
-	resp, err := client.Do(req)
+	err := receive(context.Background(), url, opts)
+	cleanupNode()
 	if err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+		log.Printf("❌ %v", err)
+		events.emit(event{Event: "error", Error: err.Error()})
+		return errExitCode(err)
 	}
-	defer resp.Body.Close()
+	return exitOK
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("server rejected request: HTTP %d", resp.StatusCode)
+// receiveOptions sets up a receive into dir, or to output (-o; "-" for
+// stdout), that reports to the screen and the event stream.
+func receiveOptions(dir, output string) burn.ReceiveOptions {
+	opts := burn.ReceiveOptions{Dir: dir, Output: output, Status: human()}
+	if output == "-" {
+		opts.Output, opts.Writer = "", os.Stdout
+		opts.Status = os.Stderr // the payload has stdout
 	}
-
-	// Extract Filename
-	contentDisp := resp.Header.Get("Content-Disposition")
-	filename := "downloaded_file"
-	if strings.Contains(contentDisp, "filename=") {
-		parts := strings.Split(contentDisp, "filename=\"")
-		if len(parts) > 1 {
-			filename = strings.TrimSuffix(parts[1], "\"")
+	opts.OnStart = func(t burn.Transfer) error {
+		if t.Class == burn.ClassText && events != nil {
+			return fmt.Errorf("secrets are never put in the event stream; receive it without -output=json")
 		}
+		events.emit(event{Event: "transfer_started", URL: t.URL, Name: t.Name, Size: t.Size})
+		return nil
 	}
-
-	// --- AUTO-RENAME LOGIC ---
-	safeName := getSafeFilename(filename)
-	if safeName != filename {
-		fmt.Printf("⚠️  File '%s' exists. Saving as '%s' instead.\n", filename, safeName)
-	}
-	filename = safeName
-	// -------------------------
-
-	// Create File
-	out, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("cannot create file: %w", err)
-	}
-	defer out.Close()
-
-	// 2. Stream Data
-	fmt.Printf("📥 Downloading '%s'...\n", filename)
-	size, err := io.Copy(out, resp.Body)
-	if err != nil {
-		return fmt.Errorf("download interrupted: %w", err)
-	}
-	// FIX: Check Content-Length integrity
-	if resp.ContentLength > 0 && size != resp.ContentLength {
-		return fmt.Errorf("download incomplete: expected %d bytes, got %d", resp.ContentLength, size)
-	}
-	fmt.Printf("✅ Download complete (%s)\n", formatBytes(size))
-
-	// 3. Send ACK (The Kill Switch)
-	fmt.Println("📡 Sending kill signal to server...")
-	ackURL := url + "/ack"
-	ackResp, err := client.Post(ackURL, "text/plain", nil)
-	if err == nil {
-		defer ackResp.Body.Close()
-		if ackResp.StatusCode == 200 {
-			fmt.Println("💥 Server confirmed destruction.")
-		} else {
-			fmt.Println("⚠️ Server responded but did not confirm destruction.")
+	if events != nil {
+		opts.OnProgress = func(received, total int64) {
+			events.emit(event{Event: "progress", Bytes: received, Total: total})
 		}
-	} else {
-		fmt.Println("⚠️ Server may have already timed out (Link is dead).")
 	}
-	return nil
+	return opts
 }
 
-// --- HELPER: Find a unique filename (test.bin -> test-1.bin) ---
-func getSafeFilename(name string) string {
-	// If the file doesn't exist, use the original name
-	if _, err := os.Stat(name); os.IsNotExist(err) {
-		return name
+// receive runs burn.Receive, shows a secret once and reports the result.
+func receive(ctx context.Context, link string, opts burn.ReceiveOptions) error {
+	res, err := burn.Receive(ctx, link, opts)
+	if err != nil {
+		return err
 	}
-
-	// Split "test.bin" into "test" and ".bin"
-	var base, ext string
-	if idx := strings.LastIndex(name, "."); idx != -1 {
-		base = name[:idx]
-		ext = name[idx:]
-	} else {
-		base = name
+	if res.Secret != nil {
+		showSecret(os.Stdout, res.Secret)
+		clear(res.Secret)
 	}
-
-	// Loop until we find a name that doesn't exist
-	for i := 1; ; i++ {
-		newName := fmt.Sprintf("%s-%d%s", base, i, ext)
-		if _, err := os.Stat(newName); os.IsNotExist(err) {
-			return newName
-		}
+	events.emit(event{Event: "received", Name: res.Name, Digest: res.Digest, Paths: res.Paths})
+	if res.Burned {
+		events.emit(event{Event: "burned", Reason: burn.ReasonAck})
 	}
+	return nil
 }
 
-func formatBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
-	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+// showSecret prints a received secret for the terminal.
+func showSecret(w io.Writer, secret []byte) {
+	fmt.Fprintln(w, "🔑 Secret (shown once):")
+	w.Write(secret)
+	if len(secret) > 0 && secret[len(secret)-1] != '\n' {
+		fmt.Fprintln(w)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"tail-burn/burn"
+)
+
+// --- REVERSE DROP ---
+// tail-burn request hosts a one-time upload link (see burn.Server.AddRequest);
+// tail-burn fulfill answers it.
+
+// ==========================================
+// SERVER LOGIC (Requester)
+// ==========================================
+func runRequester() {
+	reqCmd := flag.NewFlagSet("request", flag.ExitOnError)
+	fromExpr := reqCmd.String("from", "", "Who may upload: login names, tag:<tag>, node:<name>, id:<stable-id>, any-of(...)/all-of(...)")
+	destDir := reqCmd.String("dir", ".", "Destination directory")
+	var expires string
+	reqCmd.StringVar(&expires, "expires", "10m", "When the request burns: a duration (90s, 36h) or a time (2026-10-20T18:00Z)")
+	reqCmd.StringVar(&expires, "timeout", "10m", "Deprecated: same as -expires (a bare number is minutes)")
+	debugMode := reqCmd.Bool("debug", false, "Enable verbose Tailscale logs")
+	stallTimeout := reqCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort an upload when the sender sends no data for this long (0 = never)")
+	minRate := reqCmd.Int64("min-rate", 0, "Abort an upload slower than this many bytes/s (0 = off)")
+	auditPath := reqCmd.String("audit", "", "Append a hash-chained JSONL record of every access to this file")
+	reqCmd.Parse(os.Args[2:])
+
+	if *fromExpr == "" {
+		fmt.Println("Usage: tail-burn request -from=<user@provider>[,tag:ci,...] [-dir=<dest>]")
+		os.Exit(1)
+	}
+	from, err := burn.ParseTarget(*fromExpr)
+	if err != nil {
+		log.Fatalf("❌ Invalid -from: %v", err)
+	}
+	if fi, err := os.Stat(*destDir); err != nil || !fi.IsDir() {
+		log.Fatalf("❌ Destination is not a directory: %s", *destDir)
+	}
+	_, deadline, err := burn.ParseSchedule("", expires, time.Now())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	audit := openAudit(*auditPath)
+	defer audit.Close()
+
+	s, cleanupNode, err := burn.NewEphemeralNode("tail-burn", os.Getenv("TS_AUTHKEY"), *debugMode)
+	if err != nil {
+		log.Fatalf("❌ Error creating node: %v", err)
+	}
+	defer cleanupNode()
+	// log.Fatalf skips defers; the node's state must not outlive us
+	fatalf := func(format string, v ...any) {
+		cleanupNode()
+		log.Fatalf(format, v...)
+	}
+
+	shutdownSignal := make(chan string, 1)
+	// From here on, Ctrl-C burns the request through shutdownSignal
+	interrupted, stopSignals := interruptible(shutdownSignal)
+	defer stopSignals()
+
+	localClient, err := s.LocalClient()
+	if err != nil {
+		fatalf("❌ %v", err)
+	}
+
+	// The peer is the one sending, so reads are stall-guarded too
+	srv := &burn.Server{
+		Client:     localClient,
+		Stall:      burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate},
+		GuardReads: true,
+		Audit:      audit,
+	}
+	fmt.Println("🔌 Joining tailnet...")
+	ctx, cancel := context.WithTimeout(interrupted, time.Until(deadline))
+	ln, err := srv.ListenTailnet(ctx, s)
+	cancel()
+	if err != nil {
+		fatalf("❌ %v", err)
+	}
+	hosted, err := srv.AddRequest(burn.Request{From: from, Dir: *destDir, Expires: deadline})
+	if err != nil {
+		fatalf("❌ %v", err)
+	}
+	go func() {
+		select {
+		case reason := <-shutdownSignal:
+			hosted.Burn(reason)
+		case <-hosted.Done():
+		}
+	}()
+
+	url := hosted.Links()[0].BrowserURL
+	fmt.Print("\033[H\033[2J")
+	fmt.Println("🔥 \033[1mtail-burn\033[0m (Request Mode)")
+	fmt.Println("-------------------------------------------")
+	fmt.Printf("📥 Saving to: %s\n", *destDir)
+	fmt.Printf("👤 From: %s\n", from)
+	fmt.Printf("⏳ Burns: %s (in %s)\n", deadline.Format("2006-01-02 15:04:05 MST"), burn.FormatRemaining(time.Until(deadline)))
+	fmt.Println("-------------------------------------------")
+	fmt.Printf("🌐 Browser Link: \033[32m%s\033[0m\n", url)
+	fmt.Printf("💻 Command:      \033[33mtail-burn fulfill '%s' <file>\033[0m\n", url)
+	fmt.Println("\n(Waiting...)")
+
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			log.Printf("❌ Server error: %v", err)
+		}
+	}()
+
+	<-hosted.Done()
+	fmt.Printf("\n🛑 Shutting down: %s\n", hosted.Reason())
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+}
+
+// ==========================================
+// CLIENT LOGIC (Uploader)
+// ==========================================
+func runFulfill() {
+	fulCmd := flag.NewFlagSet("fulfill", flag.ExitOnError)
+	stallTimeout := fulCmd.Duration("stall-timeout", burn.DefaultStallPolicy.Idle, "Abort when the requester takes no data for this long (0 = never)")
+	minRate := fulCmd.Int64("min-rate", 0, "Abort an upload slower than this many bytes/s (0 = off)")
+	fromLogin := fulCmd.String("from", "", "Only upload to a tail-burn node owned by this login")
+	fromTag := fulCmd.String("from-tag", "", "Only upload to a tail-burn node carrying this tag")
+	fulCmd.Parse(os.Args[2:])
+
+	if fulCmd.NArg() != 2 {
+		fmt.Println("Usage: tail-burn fulfill [-from=<login>] [-from-tag=<tag>] <url> <file>")
+		os.Exit(1)
+	}
+	opts := burn.ReceiveOptions{
+		Stall:  burn.StallPolicy{Idle: *stallTimeout, MinRate: *minRate},
+		From:   burn.SenderCheck{Login: *fromLogin, Tag: *fromTag},
+		Status: os.Stdout,
+	}
+	if err := burn.Fulfill(context.Background(), fulCmd.Arg(0), fulCmd.Arg(1), opts); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+	"tail-burn/burn"
+)
+
+// --- ONE-TIME SECRETS ---
+// send -text reads the secret from a hidden prompt or a pipe, never from
+// argv, where it would end up in shell history and the process list.
+
+// readSecret reads the secret from a hidden prompt on a terminal, or all of
+// stdin otherwise (one trailing newline is dropped).
+func readSecret(in *os.File) ([]byte, error) {
+	var secret []byte
+	var err error
+	if term.IsTerminal(int(in.Fd())) {
+		fmt.Fprint(os.Stderr, "🔑 Secret (input hidden): ")
+		secret, err = term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(os.Stderr)
+	} else {
+		secret, err = io.ReadAll(io.LimitReader(in, burn.MaxSecretSize+1))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) > burn.MaxSecretSize {
+		clear(secret)
+		return nil, fmt.Errorf("secret is larger than %s; send it as a file", burn.FormatBytes(burn.MaxSecretSize))
+	}
+	secret = bytes.TrimSuffix(secret, []byte("\n"))
+	secret = bytes.TrimSuffix(secret, []byte("\r"))
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret is empty")
+	}
+	return secret, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSecretFromPipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in")
+	os.WriteFile(path, []byte("s3cr3t\n"), 0600)
+	f, _ := os.Open(path)
+	defer f.Close()
+	got, err := readSecret(f)
+	if err != nil || string(got) != "s3cr3t" {
+		t.Fatalf("readSecret = %q, %v", got, err)
+	}
+
+	os.WriteFile(path, []byte("\n"), 0600)
+	f2, _ := os.Open(path)
+	defer f2.Close()
+	if _, err := readSecret(f2); err == nil {
+		t.Fatalf("expected an empty secret to be rejected")
+	}
+}